@@ -1,6 +1,8 @@
 package main
 
 import (
+	"cmp"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -8,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -116,10 +119,19 @@ func main() {
 			}
 
 			skip := rand.Int63n(ncontribs)
-			cur, err := mongoColl.Find(ctx, filter, &options.FindOptions{
-				Limit: toPtr[int64](maxcontribs),
-				Skip:  toPtr(skip),
-			})
+			pipeline := append(mongo.Pipeline{
+				{{Key: "$match", Value: filter}},
+				{{Key: "$skip", Value: skip}},
+				{{Key: "$limit", Value: maxcontribs}},
+			}, lookupCode(mongoColl)...)
+			pipeline = append(pipeline, addPermalinks())
+			cur, err := mongoColl.Aggregate(ctx, pipeline)
+			if err != nil {
+				log.Println(err.Error())
+				ctx.Status(http.StatusInternalServerError)
+				return
+			}
+			holders, err := findHolders(ctx, mongoColl)
 			if err != nil {
 				log.Println(err.Error())
 				ctx.Status(http.StatusInternalServerError)
@@ -132,6 +144,7 @@ func main() {
 					ctx.Status(http.StatusInternalServerError)
 					return
 				}
+				addAttributions([]bson.M{contrib}, holders)
 				contribs = append(contribs, contrib)
 			}
 		}
@@ -311,7 +324,9 @@ func main() {
 		ctx.JSON(http.StatusOK, apis)
 	}))
 
-	// Contributions, e. g. "/node/crypto/verify"
+	// Contributions, e. g. "/node/crypto/verify", "/go/errors/As?kind=call",
+	// "/go/syscall/Getpid?goos=linux", "/go/sync/Once?generated=true" or
+	// "/go/testing/B?category=benchmark"
 	router.GET("/api/:tech/:ns/:api", func(ctx *gin.Context) {
 		var (
 			err       error
@@ -339,7 +354,8 @@ func main() {
 			return
 		}
 
-		filter := bson.M{"locus.ident": fmt.Sprintf("%s.%s", ns, api)}
+		ident := fmt.Sprintf("%s.%s", ns, api)
+		filter := newContribsFilter(ctx, ident)
 		page, err := strconv.ParseInt(ctx.Query("page"), 10, 64)
 		if err != nil {
 			log.Println(err.Error())
@@ -347,24 +363,21 @@ func main() {
 			return
 		}
 		var perPage int64 = 6
-		opts := &options.FindOptions{
-			Limit: toPtr(perPage),
-			Skip:  toPtr(page*perPage - perPage),
-		}
-		cur, err := mongoColl.Find(ctx, filter, opts)
-		if err != nil {
+		contribs := make([]bson.M, 0)
+		if err := findContribs(ctx, mongoColl, filter, ident, page, perPage, &contribs); err != nil {
 			log.Println(err.Error())
 			ctx.Status(http.StatusInternalServerError)
 			return
 		}
-		contribs := make([]bson.M, 0)
-		if err := cur.All(ctx, &contribs); err != nil {
+		holders, err := findHolders(ctx, mongoColl)
+		if err != nil {
 			log.Println(err.Error())
 			ctx.Status(http.StatusInternalServerError)
 			return
 		}
+		addAttributions(contribs, holders)
 
-		contribsn, err := mongoColl.CountDocuments(ctx, filter)
+		contribsn, err := countContribs(ctx, mongoColl, filter)
 		if err != nil {
 			log.Println(err.Error())
 			ctx.Status(http.StatusInternalServerError)
@@ -380,6 +393,144 @@ func main() {
 		ctx.JSON(http.StatusOK, p)
 	})
 
+	// Snippets of contributions with context lines, e. g.
+	// "/go/errors/As/snippets?page=1&context=3". Filters are the same as for
+	// contributions.
+	router.GET("/api/:tech/:ns/:api/snippets", func(ctx *gin.Context) {
+		var (
+			err       error
+			mongoColl *mongo.Collection
+		)
+		mongoColl, err = mongoCollFromCtx(ctx, db_contribs)
+		if err != nil {
+			log.Println(err.Error())
+			ctx.Status(http.StatusBadRequest)
+			return
+		}
+
+		ns := ctx.Param("ns")
+		ns, err = url.QueryUnescape(ns)
+		if err != nil {
+			log.Println(err.Error())
+			ctx.Status(http.StatusBadRequest)
+			return
+		}
+		api := ctx.Param("api")
+		api, err = url.QueryUnescape(api)
+		if err != nil {
+			log.Println(err.Error())
+			ctx.Status(http.StatusBadRequest)
+			return
+		}
+		ident := fmt.Sprintf("%s.%s", ns, api)
+
+		page, err := strconv.ParseInt(ctx.Query("page"), 10, 64)
+		if err != nil {
+			log.Println(err.Error())
+			ctx.Status(http.StatusBadRequest)
+			return
+		}
+		// Lines before and after snippets
+		const maxcontext = 50
+		context := 3
+		if ctx.Query("context") != "" {
+			context, err = strconv.Atoi(ctx.Query("context"))
+			if err != nil || context < 0 || context > maxcontext {
+				ctx.Status(http.StatusBadRequest)
+				return
+			}
+		}
+
+		filter := newContribsFilter(ctx, ident)
+		var perPage int64 = 6
+		contribs := make([]model.Contrib, 0)
+		if err := findContribs(ctx, mongoColl, filter, ident, page, perPage, &contribs); err != nil {
+			log.Println(err.Error())
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+
+		holders, err := findHolders(ctx, mongoColl)
+		if err != nil {
+			log.Println(err.Error())
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+
+		snippets := make([]model.Snippet, 0)
+		for _, contrib := range contribs {
+			contrib.Attribution = attributionOf(contrib, holders)
+			snippets = append(snippets, model.NewSnippets(contrib, ident, ctx.Query("kind"), context)...)
+		}
+
+		contribsn, err := countContribs(ctx, mongoColl, filter)
+		if err != nil {
+			log.Println(err.Error())
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+		type pagination struct {
+			Snippets []model.Snippet `json:"snippets"`
+			Total    int64           `json:"total"`
+			PerPage  int64           `json:"per_page"`
+		}
+		p := pagination{snippets, contribsn, perPage}
+
+		ctx.JSON(http.StatusOK, p)
+	})
+
+	// Contribution including the full file, e. g.
+	// "/go/contribs/65f1c0a2e4b0a1b2c3d4e5f6"
+	router.GET("/api/:tech/contribs/:id", func(ctx *gin.Context) {
+		var (
+			err       error
+			mongoColl *mongo.Collection
+		)
+		mongoColl, err = mongoCollFromCtx(ctx, db_contribs)
+		if err != nil {
+			log.Println(err.Error())
+			ctx.Status(http.StatusBadRequest)
+			return
+		}
+
+		id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+		if err != nil {
+			log.Println(err.Error())
+			ctx.Status(http.StatusBadRequest)
+			return
+		}
+
+		pipeline := append(mongo.Pipeline{
+			{{Key: "$match", Value: bson.D{{Key: "_id", Value: id}}}},
+		}, lookupCode(mongoColl)...)
+		pipeline = append(pipeline, addPermalinks())
+		cur, err := mongoColl.Aggregate(ctx, pipeline)
+		if err != nil {
+			log.Println(err.Error())
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+		contribs := make([]bson.M, 0)
+		if err := cur.All(ctx, &contribs); err != nil {
+			log.Println(err.Error())
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+		if len(contribs) == 0 {
+			ctx.Status(http.StatusNotFound)
+			return
+		}
+		holders, err := findHolders(ctx, mongoColl)
+		if err != nil {
+			log.Println(err.Error())
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+		addAttributions(contribs, holders)
+
+		ctx.JSON(http.StatusOK, contribs[0])
+	})
+
 	// Any other route
 	router.NoRoute(func(ctx *gin.Context) {
 		// Route non-API requests to website
@@ -404,6 +555,252 @@ func main() {
 	_ = router.Run(addr)
 }
 
+// Filters contributions of an API by query parameters, e. g. "kind=call",
+// "goos=linux", "category=test" or "generated=true"
+func newContribsFilter(ctx *gin.Context, ident string) bson.M {
+	filter := bson.M{"locus.ident": ident}
+	// Eventually filter by kind, e. g. "call" or "type"
+	if kind := ctx.Query("kind"); kind != "" {
+		filter = bson.M{"locus": bson.M{"$elemMatch": bson.M{
+			"ident": ident,
+			"kind":  kind,
+		}}}
+	}
+	// Eventually filter by platform, e. g. "goos=linux&goarch=arm64" or
+	// "exclude_goos=windows"
+	platforms := make([]bson.M, 0)
+	switch goos, goarch := ctx.Query("goos"), ctx.Query("goarch"); {
+	case goos != "" && goarch != "":
+		platforms = append(platforms, bson.M{"platforms": fmt.Sprintf("%s/%s", goos, goarch)})
+
+	case goos != "":
+		platforms = append(platforms, bson.M{"platforms": bson.M{
+			"$regex": fmt.Sprintf("^%s/", regexp.QuoteMeta(goos)),
+		}})
+
+	case goarch != "":
+		platforms = append(platforms, bson.M{"platforms": bson.M{
+			"$regex": fmt.Sprintf("/%s$", regexp.QuoteMeta(goarch)),
+		}})
+	}
+	if goos := ctx.Query("exclude_goos"); goos != "" {
+		platforms = append(platforms, bson.M{"platforms": bson.M{
+			"$not": bson.M{"$regex": fmt.Sprintf("^%s/", regexp.QuoteMeta(goos))},
+		}})
+	}
+	if len(platforms) > 0 {
+		filter["$and"] = platforms
+	}
+	// Eventually filter by category, e. g. "production" or "benchmark"
+	if category := ctx.Query("category"); category != "" {
+		filter["category"] = category
+	}
+	// Exclude generated files unless asked for, e. g. "generated=true"
+	if generated, _ := strconv.ParseBool(ctx.Query("generated")); !generated {
+		filter["is_generated"] = bson.M{"$ne": true}
+	}
+	return filter
+}
+
+// Finds a page of contributions of an API sorted by the best score of the
+// API's locus, e. g. short and commented examples first
+func findContribs(ctx *gin.Context, mongoColl *mongo.Collection, filter bson.M, ident string, page, perPage int64, results any) error {
+	cond := bson.M{"$eq": bson.A{"$$l.ident", ident}}
+	if kind := ctx.Query("kind"); kind != "" {
+		cond = bson.M{"$and": bson.A{cond, bson.M{"$eq": bson.A{"$$l.kind", kind}}}}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$addFields", Value: bson.M{
+			"score": bson.M{"$max": bson.M{"$map": bson.M{
+				"input": bson.M{"$filter": bson.M{"input": "$locus", "as": "l", "cond": cond}},
+				"as":    "l",
+				"in":    "$$l.score",
+			}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{
+			{Key: "score", Value: -1},
+			{Key: "_id", Value: 1},
+		}}},
+		// Copies of the same file, e. g. vendored by other repositories,
+		// collapse into the best scored one
+		{{Key: "$group", Value: bson.M{
+			"_id": dedupKey,
+			"doc": bson.M{"$first": "$$ROOT"},
+			"repos": bson.M{"$addToSet": bson.M{
+				"repo_owner": "$repo_owner",
+				"repo_name":  "$repo_name",
+			}},
+		}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": bson.M{"$mergeObjects": bson.A{
+			"$doc",
+			bson.M{"also_found_in": bson.M{"$filter": bson.M{
+				"input": "$repos",
+				"as":    "r",
+				"cond": bson.M{"$not": bson.A{bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$$r.repo_owner", "$doc.repo_owner"}},
+					bson.M{"$eq": bson.A{"$$r.repo_name", "$doc.repo_name"}},
+				}}}},
+			}}},
+		}}}}},
+		{{Key: "$sort", Value: bson.D{
+			{Key: "score", Value: -1},
+			{Key: "_id", Value: 1},
+		}}},
+		{{Key: "$skip", Value: page*perPage - perPage}},
+		{{Key: "$limit", Value: perPage}},
+	}
+	pipeline = append(pipeline, lookupCode(mongoColl)...)
+	pipeline = append(pipeline, addPermalinks())
+	cur, err := mongoColl.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cur.All(ctx, results)
+}
+
+// Contributions with the same tokens are copies of each other, e. g. a
+// vendored file with a different license header. Contributions without token
+// hash are unique.
+var dedupKey = bson.M{"$ifNull": bson.A{"$token_hash", "$_id"}}
+
+// Counts contributions, copies of the same file count once
+func countContribs(ctx *gin.Context, mongoColl *mongo.Collection, filter bson.M) (int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{"_id": dedupKey}}},
+		{{Key: "$count", Value: "total"}},
+	}
+	cur, err := mongoColl.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	var counts []struct {
+		Total int64 `bson:"total"`
+	}
+	if err := cur.All(ctx, &counts); err != nil {
+		return 0, err
+	}
+	if len(counts) == 0 {
+		return 0, nil
+	}
+	return counts[0].Total, nil
+}
+
+// Looks up code of contributions stored by hash, e. g. in "go_blobs".
+// Contributions with inline code keep it.
+func lookupCode(mongoColl *mongo.Collection) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{
+			"from":         mongoColl.Name() + "_blobs",
+			"localField":   "hash",
+			"foreignField": "_id",
+			"as":           "blob",
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"code": bson.M{"$ifNull": bson.A{"$code", bson.M{"$arrayElemAt": bson.A{"$blob.code", 0}}}},
+		}}},
+		{{Key: "$unset", Value: "blob"}},
+	}
+}
+
+// Adds links to the file at the extracted commit, e. g.
+// "https://github.com/cli/cli/blob/<sha>/cmd/gh/main.go", and to lines of
+// locus, e. g. ".../main.go#L12". Contributions without commit or repository
+// web page, e. g. of local repositories, don't get links. Contributions
+// without "repo_url" are from GitHub.
+func addPermalinks() bson.D {
+	path := bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{"$filepath", "/"}},
+		"$filename",
+		bson.M{"$concat": bson.A{bson.M{"$ltrim": bson.M{"input": "$filepath", "chars": "/"}}, "/", "$filename"}},
+	}}
+	repoURL := bson.M{"$ifNull": bson.A{
+		"$repo_url",
+		bson.M{"$concat": bson.A{"https://github.com/", "$repo_owner", "/", "$repo_name"}},
+	}}
+	blobURL := bson.M{"$concat": bson.A{repoURL, "/blob/", "$commit", "/", path}}
+	hasLinks := bson.M{"$and": bson.A{
+		bson.M{"$gt": bson.A{"$commit", ""}},
+		bson.M{"$gt": bson.A{repoURL, ""}},
+	}}
+	return bson.D{{Key: "$set", Value: bson.M{
+		"blob_url": bson.M{"$cond": bson.A{hasLinks, blobURL, "$$REMOVE"}},
+		"locus": bson.M{"$cond": bson.A{
+			hasLinks,
+			bson.M{"$map": bson.M{
+				"input": "$locus",
+				"as":    "l",
+				"in": bson.M{"$mergeObjects": bson.A{"$$l", bson.M{
+					"permalink": bson.M{"$concat": bson.A{blobURL, "#L", bson.M{"$toString": "$$l.line"}}},
+				}}},
+			}},
+			"$locus",
+		}},
+	}}}
+}
+
+// Finds copyright holders of repositories by owner and name, e. g. "GitHub
+// Inc." of "cli/cli". Authors stand in for undetected holders.
+func findHolders(ctx *gin.Context, mongoColl *mongo.Collection) (map[[2]string][]string, error) {
+	holders := make(map[[2]string][]string)
+
+	var lic model.License
+	err := mongoColl.FindOne(ctx, bson.D{
+		{Key: "_id", Value: licenses_id},
+	}).Decode(&lic)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return holders, nil
+
+	case err != nil:
+		return nil, err
+	}
+
+	for _, repo := range lic.Repos {
+		if len(repo.Copyrights) > 0 {
+			holders[repo.Repo] = repo.Copyrights
+		} else if repo.Author != "" {
+			holders[repo.Repo] = []string{repo.Author}
+		}
+	}
+	return holders, nil
+}
+
+// Attaches attribution to contributions, see attributionOf
+func addAttributions(contribs []bson.M, holders map[[2]string][]string) {
+	for _, contrib := range contribs {
+		var c model.Contrib
+		c.Policy, _ = contrib["policy"].(string)
+		c.License, _ = contrib["license"].(string)
+		c.FileLicense, _ = contrib["file_license"].(string)
+		c.RepoOwner, _ = contrib["repo_owner"].(string)
+		c.RepoName, _ = contrib["repo_name"].(string)
+		if fileCopyrights, ok := contrib["file_copyrights"].(bson.A); ok {
+			for _, holder := range fileCopyrights {
+				if holder, ok := holder.(string); ok {
+					c.FileCopyrights = append(c.FileCopyrights, holder)
+				}
+			}
+		}
+		if attribution := attributionOf(c, holders); attribution != "" {
+			contrib["attribution"] = attribution
+		}
+	}
+}
+
+// Finds the attribution of a contribution, e. g. "cli/cli, Copyright (c)
+// GitHub Inc., licensed under MIT". Licenses and copyright holders of files, e. g. of vendored code,
+// take precedence over those of repositories.
+func attributionOf(contrib model.Contrib, holders map[[2]string][]string) string {
+	repoHolders := holders[[2]string{contrib.RepoOwner, contrib.RepoName}]
+	if len(contrib.FileCopyrights) > 0 {
+		repoHolders = contrib.FileCopyrights
+	}
+	license := cmp.Or(contrib.FileLicense, contrib.License)
+	return model.NewAttribution(contrib.Policy, license, repoHolders, contrib.RepoOwner, contrib.RepoName)
+}
+
 func mongoCollFromCtx(ctx *gin.Context, db string) (*mongo.Collection, error) {
 	return mongoCollFromTech(ctx.Param("tech"), db)
 }
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type (
+	Contrib struct {
+		ID             primitive.ObjectID `json:"_id" bson:"_id"`
+		AlsoFoundIn    []Repo             `json:"also_found_in,omitempty" bson:"also_found_in,omitempty"`
+		Attribution    string             `json:"attribution,omitempty" bson:"-"`               // Of every policy but deny, e. g. cli/cli, Copyright (c) GitHub Inc., licensed under MIT
+		BlobURL        string             `json:"blob_url,omitempty" bson:"blob_url,omitempty"` // https://github.com/cli/cli/blob/<sha>/cmd/gh/main.go
+		Code           string             `json:"code" bson:"code"`
+		Commit         string             `json:"commit,omitempty" bson:"commit,omitempty"`
+		Filename       string             `json:"filename" bson:"filename"`
+		Filepath       string             `json:"filepath" bson:"filepath"`
+		FileCopyrights []string           `json:"file_copyrights,omitempty" bson:"file_copyrights,omitempty"` // Of leading comments, The Go Authors.
+		FileLicense    string             `json:"file_license,omitempty" bson:"file_license,omitempty"`       // Of leading comments, BSD-3-Clause
+		License        string             `json:"license,omitempty" bson:"license,omitempty"`                 // MIT
+		Locus          []Locus            `json:"locus" bson:"locus"`
+		Policy         string             `json:"policy,omitempty" bson:"policy,omitempty"` // allow, attribute, snippet
+		RepoName       string             `json:"repo_name" bson:"repo_name"`
+		RepoOwner      string             `json:"repo_owner" bson:"repo_owner"`
+	}
+
+	// Repository of a copy of a contribution, e. g. a vendored file
+	Repo struct {
+		RepoName  string `json:"repo_name" bson:"repo_name"`
+		RepoOwner string `json:"repo_owner" bson:"repo_owner"`
+	}
+
+	Locus struct {
+		Ident     string  `json:"ident" bson:"ident"`
+		Kind      string  `json:"kind,omitempty" bson:"kind,omitempty"`
+		Line      int     `json:"line" bson:"line"`
+		Permalink string  `json:"permalink,omitempty" bson:"permalink,omitempty"` // https://github.com/cli/cli/blob/<sha>/cmd/gh/main.go#L12
+		Score     float64 `json:"score" bson:"score"`
+		Snippet   struct {
+			Start struct {
+				Line int `json:"line" bson:"line"`
+			} `json:"start" bson:"start"`
+			End struct {
+				Line int `json:"line" bson:"line"`
+			} `json:"end" bson:"end"`
+		} `json:"snippet" bson:"snippet"`
+	}
+
+	// Part of a contribution's code enclosing locus of an API, e. g. a
+	// function declaration
+	Snippet struct {
+		ContribID   primitive.ObjectID `json:"contrib_id"`
+		AlsoFoundIn []Repo             `json:"also_found_in,omitempty"`
+		Attribution string             `json:"attribution,omitempty"`
+		Code        string             `json:"code"`
+		Filename    string             `json:"filename"`
+		Filepath    string             `json:"filepath"`
+		Lines       []int              `json:"lines"`               // Lines of locus, e. g. 12
+		Permalink   string             `json:"permalink,omitempty"` // https://github.com/cli/cli/blob/<sha>/cmd/gh/main.go#L9-L20
+		StartLine   int                `json:"start_line"`          // Line of the first line of code, e. g. 9
+		RepoName    string             `json:"repo_name"`
+		RepoOwner   string             `json:"repo_owner"`
+		Score       float64            `json:"score"` // Best score of locus
+	}
+)
+
+// Creates snippets of an API's locus with context lines. Locus within the
+// same snippet share the snippet. Contributions without snippet information
+// fall back to the locus line. The kind is optional.
+func NewSnippets(contrib Contrib, ident, kind string, context int) []Snippet {
+	var (
+		lines = strings.Split(contrib.Code, "\n")
+
+		snippets = make([]Snippet, 0)
+		indices  = make(map[[2]int]int)
+	)
+	for _, locus := range contrib.Locus {
+		if locus.Ident != ident || (kind != "" && locus.Kind != kind) {
+			continue
+		}
+
+		start, end := locus.Snippet.Start.Line, locus.Snippet.End.Line
+		if start == 0 {
+			start, end = locus.Line, locus.Line
+		}
+		start = max(1, start-context)
+		end = min(len(lines), end+context)
+		if start > end {
+			continue
+		}
+
+		if i, ok := indices[[2]int{start, end}]; ok {
+			snippets[i].Lines = append(snippets[i].Lines, locus.Line)
+			snippets[i].Score = max(snippets[i].Score, locus.Score)
+			continue
+		}
+		indices[[2]int{start, end}] = len(snippets)
+		var permalink string
+		if contrib.BlobURL != "" {
+			permalink = fmt.Sprintf("%s#L%d-L%d", contrib.BlobURL, start, end)
+		}
+		snippets = append(snippets, Snippet{
+			ContribID:   contrib.ID,
+			AlsoFoundIn: contrib.AlsoFoundIn,
+			Attribution: contrib.Attribution,
+			Code:        strings.Join(lines[start-1:end], "\n"),
+			Filename:    contrib.Filename,
+			Filepath:    contrib.Filepath,
+			Lines:       []int{locus.Line},
+			Permalink:   permalink,
+			StartLine:   start,
+			RepoName:    contrib.RepoName,
+			RepoOwner:   contrib.RepoOwner,
+			Score:       locus.Score,
+		})
+	}
+
+	// Best snippets first
+	slices.SortFunc(snippets, func(a, b Snippet) int {
+		return cmp.Or(
+			cmp.Compare(b.Score, a.Score),
+			cmp.Compare(a.StartLine, b.StartLine),
+		)
+	})
+	for _, snippet := range snippets {
+		slices.Sort(snippet.Lines)
+	}
+	return snippets
+}
@@ -1,10 +1,38 @@
 package model
 
+import "strings"
+
 type License struct {
 	ID    any `json:"_id" bson:"_id"`
 	Repos []struct {
 		Author string    `json:"author" bson:"author"`
 		Repo   [2]string `json:"repo" bson:"repo"`
 		Type   string    `json:"type" bson:"type"`
+		// Detected from license files, e. g. "MIT" or "Apache-2.0 AND MIT"
+		SPDX       string   `json:"spdx,omitempty" bson:"spdx,omitempty"`
+		Copyrights []string `json:"copyrights,omitempty" bson:"copyrights,omitempty"`
+		// Detected license differs from the curated one
+		Mismatch bool `json:"mismatch,omitempty" bson:"mismatch,omitempty"`
 	} `json:"repos" bson:"repos"`
 }
+
+// Creates the attribution of a contribution, e. g. "cli/cli, Copyright (c)
+// GitHub Inc., licensed under MIT". Contributions of the "snippet" policy are
+// marked as truncated. Denied contributions have none.
+func NewAttribution(policy, license string, holders []string, repoOwner, repoName string) string {
+	if policy == "deny" {
+		return ""
+	}
+
+	attribution := repoOwner + "/" + repoName
+	if len(holders) > 0 {
+		attribution += ", Copyright (c) " + strings.Join(holders, ", ")
+	}
+	if license != "" {
+		attribution += ", licensed under " + license
+	}
+	if policy == "snippet" {
+		attribution += ", truncated to snippets"
+	}
+	return attribution
+}
@@ -3,9 +3,13 @@ package api
 
 import (
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"go/types"
 	"log"
 	"regexp"
+	"strings"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -15,11 +19,13 @@ func init() {
 }
 
 type API struct {
-	Doc   string  `json:"doc" bson:"doc"`                         // ToUpper returns s with all Unicode letters mapped to their upper case.
-	Name  string  `json:"name" bson:"name"`                       // Reader, Writer, Buffer
-	Ns    string  `json:"ns" bson:"ns"`                           // compress/lzw, net, bytes
-	Type  string  `json:"type" bson:"type"`                       // struct, error, int, map, func
-	Value *string `json:"value,omitempty" bson:"value,omitempty"` // NewFlagSet(os.Args[0], ExitOnError), 512, errors.New("bytes.Buffer: too large")
+	Doc        string  `json:"doc" bson:"doc"`                                     // ToUpper returns s with all Unicode letters mapped to their upper case.
+	Name       string  `json:"name" bson:"name"`                                   // Reader, Writer, Buffer, Buffer.Grow
+	Ns         string  `json:"ns" bson:"ns"`                                       // compress/lzw, net, bytes
+	Parent     string  `json:"parent,omitempty" bson:"parent,omitempty"`           // Buffer, Request
+	Type       string  `json:"type" bson:"type"`                                   // struct, error, int, map, func, method, field
+	TypeParams string  `json:"type_params,omitempty" bson:"type_params,omitempty"` // [S ~[]E, E cmp.Ordered], [T any]
+	Value      *string `json:"value,omitempty" bson:"value,omitempty"`             // NewFlagSet(os.Args[0], ExitOnError), 512, errors.New("bytes.Buffer: too large")
 }
 
 func (api API) ID() string {
@@ -27,13 +33,16 @@ func (api API) ID() string {
 }
 
 func Get() []API {
-	pkgs := getAllPkgs()
+	pkgs, docs := getAllPkgs()
 	stripePkgs(pkgs)
-	return getAPIs(pkgs)
+	return getAPIs(pkgs, docs)
 }
 
-func getAPIs(pkgs map[string][]types.Object) []API {
-	apis := make([]API, 0)
+func getAPIs(pkgs map[string][]types.Object, docs map[string]string) []API {
+	var (
+		apis     = make([]API, 0)
+		membersn int
+	)
 
 	for pkg, objs := range pkgs {
 		for _, obj := range objs {
@@ -42,6 +51,7 @@ func getAPIs(pkgs map[string][]types.Object) []API {
 			}
 
 			api := API{
+				Doc:  docs[fmt.Sprintf("%s.%s", pkg, obj.Name())],
 				Name: obj.Name(),
 				Ns:   pkg,
 			}
@@ -118,8 +128,13 @@ func getAPIs(pkgs map[string][]types.Object) []API {
 
 			case *types.Func:
 				api.Type = "func"
+				api.TypeParams = formatTypeParams(o.Type().(*types.Signature).TypeParams(), o.Pkg())
 
 			case *types.TypeName:
+				if named, ok := o.Type().(*types.Named); ok && !o.IsAlias() {
+					api.TypeParams = formatTypeParams(named.TypeParams(), o.Pkg())
+				}
+
 				switch typ := o.Type().Underlying().(type) {
 				case *types.Struct:
 					api.Type = "struct"
@@ -158,27 +173,252 @@ func getAPIs(pkgs map[string][]types.Object) []API {
 			log.Printf("type: %s", api.Type)
 
 			apis = append(apis, api)
+
+			// Methods and fields are counted only, they're too many to log
+			if typeName, ok := obj.(*types.TypeName); ok {
+				members := getMemberAPIs(pkg, typeName, docs)
+				membersn += len(members)
+				apis = append(apis, members...)
+			}
 		}
 	}
+	log.Printf("members: %d", membersn)
 
 	return apis
 }
 
-func getAllPkgs() map[string][]types.Object {
+func getMemberAPIs(pkg string, typeName *types.TypeName, docs map[string]string) []API {
+	apis := make([]API, 0)
+
+	named, ok := typeName.Type().(*types.Named)
+	if !ok || typeName.IsAlias() {
+		return apis
+	}
+
+	// Value and pointer receivers. Pointers to interfaces have no methods.
+	var mset *types.MethodSet
+	switch named.Underlying().(type) {
+	case *types.Interface:
+		mset = types.NewMethodSet(named)
+
+	default:
+		mset = types.NewMethodSet(types.NewPointer(named))
+	}
+	for i := range mset.Len() {
+		selection := mset.At(i)
+		method := selection.Obj()
+		if !method.Exported() || !isDeclaredBy(named, selection) {
+			continue
+		}
+
+		apis = append(apis, API{
+			Doc:    docs[declaredIdent(method)],
+			Name:   fmt.Sprintf("%s.%s", named.Obj().Name(), method.Name()),
+			Ns:     pkg,
+			Parent: named.Obj().Name(),
+			Type:   "method",
+		})
+	}
+
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return apis
+	}
+	for field := range st.Fields() {
+		if !field.Exported() {
+			continue
+		}
+
+		apis = append(apis, API{
+			Doc:    docs[fmt.Sprintf("%s.%s.%s", pkg, named.Obj().Name(), field.Name())],
+			Name:   fmt.Sprintf("%s.%s", named.Obj().Name(), field.Name()),
+			Ns:     pkg,
+			Parent: named.Obj().Name(),
+			Type:   "field",
+		})
+	}
+
+	return apis
+}
+
+// Formats type parameters with their constraints, e. g. "[S ~[]E, E cmp.Ordered]"
+// for "slices.Max"
+func formatTypeParams(typeParams *types.TypeParamList, pkg *types.Package) string {
+	if typeParams.Len() == 0 {
+		return ""
+	}
+
+	params := make([]string, 0, typeParams.Len())
+	for typeParam := range typeParams.TypeParams() {
+		constraint := types.TypeString(typeParam.Constraint(), types.RelativeTo(pkg))
+		params = append(params, fmt.Sprintf("%s %s", typeParam.Obj().Name(), constraint))
+	}
+	return fmt.Sprintf("[%s]", strings.Join(params, ", "))
+}
+
+// Reports whether the selected method belongs to the named type. Methods
+// promoted from exported embedded types belong to the embedded type, e. g.
+// "bufio.ReadWriter.Flush" is "bufio.Writer.Flush", whereas methods promoted
+// from unexported embedded types belong to the embedding type, e. g.
+// "testing.T.Fatal".
+func isDeclaredBy(named *types.Named, selection *types.Selection) bool {
+	typ := types.Type(named)
+	indices := selection.Index()
+	for _, index := range indices[:len(indices)-1] {
+		st, ok := typ.Underlying().(*types.Struct)
+		if !ok {
+			return false
+		}
+		typ = st.Field(index).Type()
+		if ptr, ok := typ.(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+		if embedded, ok := typ.(*types.Named); ok && embedded.Obj().Exported() {
+			return false
+		}
+	}
+	return true
+}
+
+// Identifier of the method's declaration, e. g. "io.Closer.Close" for
+// "io.ReadCloser.Close"
+func declaredIdent(method types.Object) string {
+	sig, ok := method.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return ""
+	}
+	recv := sig.Recv().Type()
+	if ptr, ok := recv.(*types.Pointer); ok {
+		recv = ptr.Elem()
+	}
+	named, ok := recv.(*types.Named)
+	// Universe "error"
+	if !ok || named.Obj().Pkg() == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s.%s.%s", named.Obj().Pkg().Path(), named.Obj().Name(), method.Name())
+}
+
+func getAllPkgs() (map[string][]types.Object, map[string]string) {
 	stdPackages := func() []*packages.Package {
-		pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedTypes}, "std")
+		pkgs, err := packages.Load(&packages.Config{
+			Mode: packages.NeedName | packages.NeedFiles | packages.NeedTypes,
+		}, "std")
 		checkErr(err)
 		return pkgs
 	}
-	pkgs := make(map[string][]types.Object)
+	var (
+		pkgs = make(map[string][]types.Object)
+		docs = make(map[string]string)
+	)
 	for _, pkg := range stdPackages() {
 		log.Printf("pkg: %s", pkg.ID)
 		for _, name := range pkg.Types.Scope().Names() {
 			log.Printf("name: %s", name)
 			pkgs[pkg.ID] = append(pkgs[pkg.ID], pkg.Types.Scope().Lookup(name))
 		}
+		findDocs(pkg.ID, pkg.GoFiles, docs)
+	}
+	return pkgs, docs
+}
+
+// Finds doc comments of package-level declarations, methods, struct fields
+// and interface methods, e. g. "bytes.Buffer.Grow"
+func findDocs(pkg string, files []string, docs map[string]string) {
+	var (
+		fset = token.NewFileSet()
+
+		add = func(ident string, groups ...*ast.CommentGroup) {
+			for _, group := range groups {
+				if group != nil {
+					docs[fmt.Sprintf("%s.%s", pkg, ident)] = group.Text()
+					return
+				}
+			}
+		}
+	)
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			log.Println(err.Error())
+			continue
+		}
+
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					add(d.Name.Name, d.Doc)
+					continue
+				}
+				if recv := recvName(d.Recv.List[0].Type); recv != "" {
+					add(fmt.Sprintf("%s.%s", recv, d.Name.Name), d.Doc)
+				}
+
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						add(s.Name.Name, s.Doc, d.Doc)
+
+						var fields *ast.FieldList
+						switch t := s.Type.(type) {
+						case *ast.StructType:
+							fields = t.Fields
+
+						case *ast.InterfaceType:
+							fields = t.Methods
+
+						default:
+							continue
+						}
+						for _, field := range fields.List {
+							names := field.Names
+							// Embedded field, but not embedded interface
+							if _, ok := s.Type.(*ast.StructType); ok && len(names) == 0 {
+								if name := recvName(field.Type); name != "" {
+									names = []*ast.Ident{ast.NewIdent(name)}
+								}
+							}
+							for _, name := range names {
+								add(fmt.Sprintf("%s.%s", s.Name.Name, name.Name), field.Doc, field.Comment)
+							}
+						}
+
+					case *ast.ValueSpec:
+						for _, name := range s.Names {
+							add(name.Name, s.Doc, s.Comment, d.Doc)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// Name of a receiver or embedded type, e. g. "Buffer" for "*Buffer",
+// "Pointer" for "*Pointer[T]" or "Reader" for "io.Reader"
+func recvName(expr ast.Expr) string {
+	switch typ := expr.(type) {
+	case *ast.Ident:
+		return typ.Name
+
+	case *ast.StarExpr:
+		return recvName(typ.X)
+
+	case *ast.IndexExpr:
+		return recvName(typ.X)
+
+	case *ast.IndexListExpr:
+		return recvName(typ.X)
+
+	case *ast.SelectorExpr:
+		return typ.Sel.Name
+
+	case *ast.ParenExpr:
+		return recvName(typ.X)
 	}
-	return pkgs
+	return ""
 }
 
 func stripePkgs(pkgs map[string][]types.Object) {
@@ -22,6 +22,12 @@ func newDoc(api goapis.API) bson.D {
 		bson.E{Key: "type", Value: api.Type},
 		bson.E{Key: "ns", Value: api.Ns},
 	}
+	if api.Parent != "" {
+		doc = append(doc, bson.E{Key: "parent", Value: api.Parent})
+	}
+	if api.TypeParams != "" {
+		doc = append(doc, bson.E{Key: "type_params", Value: api.TypeParams})
+	}
 	if api.Value != nil {
 		doc = append(doc, bson.E{Key: "value", Value: *api.Value})
 	}
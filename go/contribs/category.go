@@ -0,0 +1,74 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"contribs-go/model"
+)
+
+// Finds the category of a file by its name and test function declarations.
+// Test files are categorized by their most frequent kind of test function,
+// e. g. "benchmark" for mostly "func BenchmarkX(b *testing.B)". Ties favor
+// examples, fuzz tests, benchmarks and tests in this order.
+func findCategory(name string, src []byte) string {
+	if !strings.HasSuffix(name, "_test.go") {
+		return model.CategoryProduction
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), name, src, parser.SkipObjectResolution)
+	if err != nil {
+		return model.CategoryTest
+	}
+
+	var (
+		categories = []struct {
+			prefix   string
+			category string
+		}{
+			{"Example", model.CategoryExample},
+			{"Fuzz", model.CategoryFuzz},
+			{"Benchmark", model.CategoryBenchmark},
+			{"Test", model.CategoryTest},
+		}
+		counts = make(map[string]int)
+	)
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Recv != nil {
+			continue
+		}
+		for _, c := range categories {
+			if isTestFunc(funcDecl.Name.Name, c.prefix) {
+				counts[c.category]++
+				break
+			}
+		}
+	}
+
+	// Test helpers without test functions are tests
+	category, maxcount := model.CategoryTest, 0
+	for _, c := range categories {
+		if counts[c.category] > maxcount {
+			category, maxcount = c.category, counts[c.category]
+		}
+	}
+	return category
+}
+
+// Reports whether the function name is a test function name as recognized by
+// "go test", e. g. "TestClient" or "Test_client", but not "Testify"
+func isTestFunc(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(r)
+}
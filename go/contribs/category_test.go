@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+
+	"contribs-go/model"
+)
+
+func TestFindCategory(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		src  string
+		want string
+	}{
+		{
+			name: "production",
+			file: "client.go",
+			src:  "package client\n\nfunc TestClient() {}",
+			want: model.CategoryProduction,
+		},
+		{
+			name: "test",
+			file: "client_test.go",
+			src:  "package client\n\nfunc TestClient(t *testing.T) {}\n\nfunc Test_get(t *testing.T) {}\n\nfunc BenchmarkClient(b *testing.B) {}",
+			want: model.CategoryTest,
+		},
+		{
+			name: "benchmark",
+			file: "client_test.go",
+			src:  "package client\n\nfunc BenchmarkGet(b *testing.B) {}\n\nfunc BenchmarkPost(b *testing.B) {}\n\nfunc TestClient(t *testing.T) {}",
+			want: model.CategoryBenchmark,
+		},
+		{
+			name: "fuzz",
+			file: "parse_test.go",
+			src:  "package parse\n\nfunc FuzzParse(f *testing.F) {}",
+			want: model.CategoryFuzz,
+		},
+		{
+			name: "example",
+			file: "example_test.go",
+			src:  "package client_test\n\nfunc Example() {}\n\nfunc ExampleClient_Get() {}\n\nfunc TestClient(t *testing.T) {}",
+			want: model.CategoryExample,
+		},
+		{
+			name: "tie",
+			file: "client_test.go",
+			src:  "package client\n\nfunc TestClient(t *testing.T) {}\n\nfunc ExampleClient() {}",
+			want: model.CategoryExample,
+		},
+		{
+			name: "helpers",
+			file: "helpers_test.go",
+			src:  "package client\n\nfunc Testify() {}\n\nfunc newServer() {}",
+			want: model.CategoryTest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findCategory(tt.file, []byte(tt.src)); got != tt.want {
+				t.Errorf("findCategory() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
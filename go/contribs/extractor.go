@@ -1,13 +1,16 @@
 package main
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"go/ast"
-	"go/importer"
 	"go/parser"
 	"go/token"
 	"go/types"
+	filepat "path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
 	"contribs-go/model"
@@ -15,24 +18,47 @@ import (
 
 type extractor struct {
 	Error error
+	// Syntax errors of files skipped, e. g. "main.go:3:1: expected
+	// declaration, found fmt". Sibling files are extracted regardless.
+	SyntaxErrors []error
+
+	// Type errors, e. g. unresolved imports. Locus of correctly resolved
+	// APIs is extracted regardless.
+	diagnostics []types.Error
 
 	fset  *token.FileSet
-	nodes ast.Node
+	files []*ast.File
 
 	info *types.Info
+	pkg  *types.Package
+}
+
+// Source file of a package
+type srcFile struct {
+	name string
+	src  []byte
 }
 
 func newExtractor(src []byte) *extractor {
+	return newPkgExtractor([]srcFile{{name: "main.go", src: src}}, hostPlatform, nil)
+}
+
+// Type-checks files of the same package together, so identifiers declared
+// in sibling files resolve. Imports and sizes are those of the platform.
+// Imports are resolved from the module, if any, first. Files with syntax
+// errors are skipped.
+func newPkgExtractor(srcFiles []srcFile, platform platform, mod *module) *extractor {
 	ex := &extractor{}
 
-	srcFile, fset, err := parse(src)
-	if err != nil {
-		ex.Error = err
+	files, fset, errs := parse(srcFiles)
+	if len(files) == 0 {
+		ex.Error = errors.Join(errs...)
 		return ex
 	}
+	ex.SyntaxErrors = errs
 
 	ex.fset = fset
-	ex.nodes = srcFile
+	ex.files = files
 	defer func() {
 		if r := recover(); r != nil {
 			switch typ := r.(type) {
@@ -45,36 +71,91 @@ func newExtractor(src []byte) *extractor {
 		}
 	}()
 
-	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	imp := newSrcImporter(fset, platform, mod)
+	// External test packages import test variants, e. g. including
+	// declarations of "export_test.go"
+	if name := files[0].Name.Name; mod != nil && strings.HasSuffix(name, "_test") {
+		imp.forTest = mod.importPath(filepat.Dir(fset.Position(files[0].Package).Filename))
+	}
+	conf := types.Config{
+		Importer: imp,
+		Sizes:    types.SizesFor("gc", platform.goarch),
+		Error: func(err error) {
+			if typeErr, ok := err.(types.Error); ok {
+				ex.diagnostics = append(ex.diagnostics, typeErr)
+			}
+		},
+	}
 	conf.DisableUnusedImportCheck = true
 	info := &types.Info{
-		Types: make(map[ast.Expr]types.TypeAndValue),
-	}
-	_, err = conf.Check("main", fset, []*ast.File{srcFile}, info)
-	if err != nil {
-		ex.Error = err
-		return ex
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Instances:  make(map[*ast.Ident]types.Instance),
 	}
+	// Type errors are collected as diagnostics and leave info partially
+	// populated
+	pkg, _ := conf.Check("main", fset, files, info)
 	ex.info = info
+	ex.pkg = pkg
+	ex.diagnoseStubs(imp.stubs)
 
 	return ex
 }
 
+// Extracts locus of all files
 func (ex *extractor) Extract() map[model.Locus]struct{} {
 	locus := make(map[model.Locus]struct{})
+	for _, fileLocus := range ex.ExtractFiles() {
+		for l := range fileLocus {
+			locus[l] = struct{}{}
+		}
+	}
+	return locus
+}
+
+// Extracts locus by file name
+func (ex *extractor) ExtractFiles() map[string]map[model.Locus]struct{} {
+	locus := make(map[string]map[model.Locus]struct{})
 
 	if ex.Error != nil || ex.info == nil {
 		return locus
 	}
 
+	parents := make(map[ast.Node]ast.Node)
+	for _, file := range ex.files {
+		stack := make([]ast.Node, 0)
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n == nil {
+				stack = stack[:len(stack)-1]
+				return true
+			}
+			if len(stack) > 0 {
+				parents[n] = stack[len(stack)-1]
+			}
+			stack = append(stack, n)
+			return true
+		})
+	}
+
 	var (
-		newAPI = func(pos token.Pos, imporSpec *ast.ImportSpec, idents ...string) model.Locus {
+		add = func(pos token.Pos, ident, kind string, node ast.Node, typeArgs *types.TypeList) {
 			tokPos := ex.fset.Position(pos)
-			pkg := strings.Trim(imporSpec.Path.Value, "\"")
-			return model.Locus{
-				Ident: fmt.Sprintf("%s.%s", pkg, strings.Join(idents, ".")),
-				Line:  tokPos.Line,
+			if _, ok := locus[tokPos.Filename]; !ok {
+				locus[tokPos.Filename] = make(map[model.Locus]struct{})
 			}
+			l := model.Locus{
+				Ident:    ident,
+				Kind:     kind,
+				TypeArgs: ex.formatTypeArgs(typeArgs),
+				Line:     tokPos.Line,
+				Span: model.Span{
+					Start: ex.newPos(node.Pos()),
+					End:   ex.newPos(node.End()),
+				},
+				Snippet: ex.findSnippet(node, parents),
+			}
+			locus[tokPos.Filename][l] = struct{}{}
 		}
 
 		resolveSelExpr = func(expr *ast.SelectorExpr) (*ast.Ident, *ast.Ident) {
@@ -96,83 +177,368 @@ func (ex *extractor) Extract() map[model.Locus]struct{} {
 				continue
 			}
 
-			switch x.Obj {
-			// Exclude references
-			case nil:
-				imporSpec := ex.findImport(x)
-				switch imporSpec {
-				case nil:
-					// Reference must be in another file
-					continue
-				}
+			// Exclude references other than imports, e. g. variables
+			// declared in sibling files
+			pkgName, ok := ex.info.Uses[x].(*types.PkgName)
+			if !ok {
+				continue
+			}
 
-				if !isStdImport(imporSpec) {
-					continue
-				}
+			pkg := pkgName.Imported().Path()
+			if !isStdPkg(pkg) {
+				continue
+			}
+
+			kind, node := ex.findKind(typ, parents)
+			// Explicit and inferred instantiations, e. g. "slices.Max(ints)"
+			typeArgs := ex.info.Instances[sel].TypeArgs
+			add(sel.Pos(), fmt.Sprintf("%s.%s", pkg, sel.Name), kind, node, typeArgs)
+		}
+	}
+
+	// Methods and fields, e. g. "buf.WriteString" or "resp.Body"
+	for expr, selection := range ex.info.Selections {
+		recv := findRecv(selection)
+		switch recv {
+		case nil:
+			continue
+		}
+
+		pkg := recv.Obj().Pkg()
+		if pkg == nil || !isStdPkg(pkg.Path()) {
+			continue
+		}
+		if !selection.Obj().Exported() {
+			continue
+		}
 
-				l := newAPI(sel.Pos(), imporSpec, sel.Name)
-				locus[l] = struct{}{}
+		kind, node := ex.findKind(expr, parents)
+		// Receiver instantiation, e. g. "p.Load()" with "p" being
+		// "atomic.Pointer[Config]"
+		add(expr.Sel.Pos(), fmt.Sprintf("%s.%s.%s", pkg.Path(), recv.Obj().Name(), expr.Sel.Name), kind, node, recv.TypeArgs())
+	}
+
+	for _, file := range ex.files {
+		dotImports := make(map[string]struct{})
+		for _, importSpec := range file.Imports {
+			pkg, err := strconv.Unquote(importSpec.Path.Value)
+			if err != nil || !isStdPkg(pkg) || importSpec.Name == nil {
+				continue
+			}
+
+			switch importSpec.Name.Name {
+			// Side effects, e. g. "import _ \"net/http/pprof\""
+			case "_":
+				add(importSpec.Pos(), pkg, model.KindImport, importSpec, nil)
+
+			case ".":
+				dotImports[pkg] = struct{}{}
+			}
+		}
+		if len(dotImports) == 0 {
+			continue
+		}
+
+		// Dot-imports, e. g. "HasPrefix" with "import . \"strings\""
+		var inspect func(n ast.Node) bool
+		inspect = func(n ast.Node) bool {
+			switch node := n.(type) {
+			// Selected identifiers aren't dot-imported
+			case *ast.SelectorExpr:
+				ast.Inspect(node.X, inspect)
+				return false
+
+			case *ast.Ident:
+				obj := ex.info.Uses[node]
+				if obj == nil || obj.Pkg() == nil || obj.Parent() != obj.Pkg().Scope() {
+					return true
+				}
+				if _, ok := dotImports[obj.Pkg().Path()]; ok {
+					kind, expr := ex.findKind(node, parents)
+					typeArgs := ex.info.Instances[node].TypeArgs
+					add(node.Pos(), fmt.Sprintf("%s.%s", obj.Pkg().Path(), node.Name), kind, expr, typeArgs)
+				}
 			}
+			return true
 		}
+		ast.Inspect(file, inspect)
 	}
 
 	return locus
 }
 
-func (ex *extractor) findImport(x *ast.Ident) *ast.ImportSpec {
-	var impSpec *ast.ImportSpec
+// Replaces type errors of selectors of stubbed packages, e. g.
+// "undefined: yaml.Unmarshal", by a diagnostic of the import
+func (ex *extractor) diagnoseStubs(stubs map[string]*types.Package) {
+	if len(stubs) == 0 {
+		return
+	}
 
 	var (
-		eqPath = func(lit *ast.BasicLit, name string) bool {
-			if lit == nil {
-				return false
-			}
-			val := strings.Trim(lit.Value, "\"")
-			spl := strings.Split(val, "/")
-			if len(spl) > 1 {
-				val = spl[len(spl)-1]
+		diagnostics = make([]types.Error, 0)
+		stubbed     = make(map[token.Pos]struct{})
+	)
+	for _, file := range ex.files {
+		for _, importSpec := range file.Imports {
+			path, err := strconv.Unquote(importSpec.Path.Value)
+			if _, ok := stubs[path]; err != nil || !ok {
+				continue
 			}
-			return val == name
+			diagnostics = append(diagnostics, types.Error{
+				Fset: ex.fset,
+				Pos:  importSpec.Path.Pos(),
+				Msg:  fmt.Sprintf("could not import %s (stubbed)", path),
+				Soft: true,
+			})
 		}
-		eqName = func(ident *ast.Ident, name string) bool {
-			if ident == nil {
-				return false
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			x, ok := sel.X.(*ast.Ident)
+			if !ok {
+				return true
 			}
-			return ident.Name == name
+			if pkgName, ok := ex.info.Uses[x].(*types.PkgName); ok {
+				if _, ok := stubs[pkgName.Imported().Path()]; ok {
+					stubbed[sel.Sel.Pos()] = struct{}{}
+				}
+			}
+			return true
+		})
+	}
+	for _, diagnostic := range ex.diagnostics {
+		if _, ok := stubbed[diagnostic.Pos]; !ok {
+			diagnostics = append(diagnostics, diagnostic)
 		}
+	}
+	slices.SortStableFunc(diagnostics, func(a, b types.Error) int {
+		return cmp.Compare(a.Pos, b.Pos)
+	})
+	ex.diagnostics = diagnostics
+}
+
+// Diagnostics by file name, e. g. "12:2: undefined: proto.Marshal". Files
+// without type errors aren't contained.
+func (ex *extractor) Diagnostics() map[string][]string {
+	// Type errors of generated or broken files can be excessive
+	const maxdiagnostics = 10
+
+	diagnostics := make(map[string][]string)
+	for _, diagnostic := range ex.diagnostics {
+		pos := diagnostic.Fset.Position(diagnostic.Pos)
+		if len(diagnostics[pos.Filename]) == maxdiagnostics {
+			continue
+		}
+		diagnostics[pos.Filename] = append(diagnostics[pos.Filename], fmt.Sprintf("%d:%d: %s", pos.Line, pos.Column, diagnostic.Msg))
+	}
+	return diagnostics
+}
+
+// Finds the kind of usage from the surrounding nodes and the type and value
+// of the expression, e. g. "call" for "time.Now()" or "type" for
+// "var t time.Time". Returns the enclosing node, e. g. the call.
+func (ex *extractor) findKind(expr ast.Expr, parents map[ast.Node]ast.Node) (string, ast.Node) {
+	var (
+		child  ast.Node = expr
+		parent          = parents[expr]
 	)
+	// Skip parentheses and instantiations, e. g. "(time.Now)()" or
+	// "atomic.Pointer[T]"
+loop:
+	for {
+		switch p := parent.(type) {
+		case *ast.ParenExpr:
 
-	ast.Inspect(ex.nodes, func(n ast.Node) bool {
-		switch typ := n.(type) {
-		case *ast.ImportSpec:
-			// Alias
-			if eqPath(typ.Path, x.Name) {
-				impSpec = typ
-				return false
+		case *ast.IndexExpr:
+			if p.X != child {
+				break loop
 			}
 
-			// Normal
-			if eqName(typ.Name, x.Name) {
-				impSpec = typ
-				return false
+		case *ast.IndexListExpr:
+			if p.X != child {
+				break loop
 			}
+
+		default:
+			break loop
 		}
+		child, parent = parent, parents[parent]
+	}
+
+	tv := ex.info.Types[expr]
+	switch p := parent.(type) {
+	case *ast.CallExpr:
+		if p.Fun != child {
+			break
+		}
+		if tv.IsType() {
+			return model.KindConversion, p
+		}
+		return model.KindCall, p
+
+	case *ast.CompositeLit:
+		if p.Type == child {
+			return model.KindComposite, p
+		}
+
+	// Embedded pointer, e. g. "*http.Client"
+	case *ast.StarExpr:
+		if field, ok := parents[p].(*ast.Field); ok && isEmbedded(field, parents) {
+			return model.KindEmbedded, field
+		}
+
+	case *ast.Field:
+		if p.Type == child && isEmbedded(p, parents) {
+			return model.KindEmbedded, p
+		}
+	}
+
+	if tv.IsType() {
+		return model.KindType, child
+	}
+
+	if sel, ok := expr.(*ast.SelectorExpr); ok {
+		if selection, ok := ex.info.Selections[sel]; ok && selection.Kind() != types.FieldVal {
+			return model.KindMethodValue, child
+		}
+	}
+
+	return model.KindValue, child
+}
+
+// Finds the span of the enclosing top-level declaration including its doc
+// comment, e. g. the function declaration
+func (ex *extractor) findSnippet(node ast.Node, parents map[ast.Node]ast.Node) model.Span {
+	for {
+		parent, ok := parents[node]
+		if !ok {
+			break
+		}
+		if _, ok := parent.(*ast.File); ok {
+			break
+		}
+		node = parent
+	}
+
+	start := node.Pos()
+	switch decl := node.(type) {
+	case *ast.FuncDecl:
+		if decl.Doc != nil {
+			start = decl.Doc.Pos()
+		}
+
+	case *ast.GenDecl:
+		if decl.Doc != nil {
+			start = decl.Doc.Pos()
+		}
+	}
+	return model.Span{
+		Start: ex.newPos(start),
+		End:   ex.newPos(node.End()),
+	}
+}
+
+// Reports whether the field is embedded in a struct or interface, as opposed
+// to unnamed parameters and results
+func isEmbedded(field *ast.Field, parents map[ast.Node]ast.Node) bool {
+	if len(field.Names) > 0 {
+		return false
+	}
+	switch parents[parents[field]].(type) {
+	case *ast.StructType, *ast.InterfaceType:
 		return true
-	})
+	}
+	return false
+}
+
+// Formats type arguments, e. g. "[]int, time.Duration"
+func (ex *extractor) formatTypeArgs(typeArgs *types.TypeList) string {
+	if typeArgs.Len() == 0 {
+		return ""
+	}
+
+	qualifier := func(pkg *types.Package) string {
+		if pkg == ex.pkg {
+			return ""
+		}
+		return pkg.Name()
+	}
+	args := make([]string, 0, typeArgs.Len())
+	for typ := range typeArgs.Types() {
+		args = append(args, types.TypeString(typ, qualifier))
+	}
+	return strings.Join(args, ", ")
+}
 
-	return impSpec
+func (ex *extractor) newPos(pos token.Pos) model.Pos {
+	tokPos := ex.fset.Position(pos)
+	return model.Pos{
+		Line:   tokPos.Line,
+		Column: tokPos.Column,
+		Offset: tokPos.Offset,
+	}
+}
+
+// Finds the exported named type declaring the selected method or field.
+// Promoted methods and fields resolve to the embedded type, e. g. "rw.Flush"
+// with "rw" being "*bufio.ReadWriter" resolves to "bufio.Writer". Unexported
+// embedded types resolve to the embedding type, e. g. "t.Fatal" resolves to
+// "testing.T" instead of "testing.common".
+func findRecv(selection *types.Selection) *types.Named {
+	var (
+		recv *types.Named
+
+		typ     = selection.Recv()
+		indices = selection.Index()
+	)
+	for i, index := range indices {
+		typ = deref(typ)
+		if named, ok := typ.(*types.Named); ok && named.Obj().Exported() {
+			recv = named
+		}
+		if i == len(indices)-1 {
+			break
+		}
+
+		// Embedded field
+		st, ok := typ.Underlying().(*types.Struct)
+		if !ok {
+			return nil
+		}
+		typ = st.Field(index).Type()
+	}
+
+	return recv
 }
 
-func isStdImport(importSpec *ast.ImportSpec) bool {
-	_, ok := gopkgs[strings.Trim(importSpec.Path.Value, "\"")]
+func deref(typ types.Type) types.Type {
+	if ptr, ok := types.Unalias(typ).(*types.Pointer); ok {
+		return types.Unalias(ptr.Elem())
+	}
+	return types.Unalias(typ)
+}
+
+func isStdPkg(path string) bool {
+	_, ok := gopkgs[path]
 	return ok
 }
 
-func parse(src []byte) (*ast.File, *token.FileSet, error) {
+// Parses files, skipping files with syntax errors
+func parse(srcFiles []srcFile) ([]*ast.File, *token.FileSet, []error) {
 	fset := token.NewFileSet()
-	srcFile, err := parser.ParseFile(fset, "main.go", src, 0)
-	if err != nil {
-		return nil, nil, err
+	files := make([]*ast.File, 0, len(srcFiles))
+	errs := make([]error, 0)
+	for _, srcFile := range srcFiles {
+		// Comments are part of snippets
+		file, err := parser.ParseFile(fset, srcFile.name, srcFile.src, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		files = append(files, file)
 	}
-	return srcFile, fset, nil
+	return files, fset, errs
 }
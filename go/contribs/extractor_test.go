@@ -18,7 +18,7 @@ func TestExtractor_Extract(t *testing.T) {
 				t.Fatal(ex.Error)
 			}
 
-			if got := ex.Extract(); !reflect.DeepEqual(got, tt.want) {
+			if got := withoutSpans(ex.Extract()); !reflect.DeepEqual(got, tt.want) {
 				if ex.Error != nil {
 					t.Fatal(ex.Error)
 				}
@@ -47,40 +47,74 @@ func getTests(t *testing.T) []struct {
 			want: map[model.Locus]struct{}{
 				{
 					Ident: "os.Chtimes",
+					Kind:  model.KindCall,
 					Line:  42,
 				}: {},
 				{
 					Ident: "syscall.Timespec",
+					Kind:  model.KindComposite,
 					Line:  15,
 				}: {},
 				{
 					Ident: "time.Time",
+					Kind:  model.KindType,
 					Line:  11,
 				}: {},
 				{
 					Ident: "time.Unix",
+					Kind:  model.KindCall,
 					Line:  14,
 				}: {},
 				{
 					Ident: "time.Unix",
+					Kind:  model.KindCall,
 					Line:  22,
 				}: {},
 				{
 					Ident: "unsafe.Sizeof",
+					Kind:  model.KindCall,
 					Line:  15,
 				}: {},
 				{
 					Ident: "time.Unix",
+					Kind:  model.KindCall,
 					Line:  25,
 				}: {},
 				{
 					Ident: "time.Time",
+					Kind:  model.KindType,
 					Line:  33,
 				}: {},
 				{
 					Ident: "time.Time",
+					Kind:  model.KindType,
 					Line:  53,
 				}: {},
+				{
+					Ident: "syscall.Timespec.Nsec",
+					Kind:  model.KindValue,
+					Line:  15,
+				}: {},
+				{
+					Ident: "time.Time.After",
+					Kind:  model.KindCall,
+					Line:  34,
+				}: {},
+				{
+					Ident: "time.Time.Before",
+					Kind:  model.KindCall,
+					Line:  34,
+				}: {},
+				{
+					Ident: "time.Time.After",
+					Kind:  model.KindCall,
+					Line:  38,
+				}: {},
+				{
+					Ident: "time.Time.Before",
+					Kind:  model.KindCall,
+					Line:  38,
+				}: {},
 			},
 		},
 		{
@@ -89,112 +123,159 @@ func getTests(t *testing.T) []struct {
 			want: map[model.Locus]struct{}{
 				{
 					Ident: "os.Chtimes",
+					Kind:  model.KindCall,
 					Line:  109,
 				}: {},
 				{
 					Ident: "os.Create",
+					Kind:  model.KindCall,
 					Line:  84,
 				}: {},
 				{
 					Ident: "os.CreateTemp",
+					Kind:  model.KindCall,
 					Line:  134,
 				}: {},
 				{
 					Ident: "path/filepath.Join",
+					Kind:  model.KindCall,
 					Line:  74,
 				}: {},
 				{
 					Ident: "path/filepath.Walk",
+					Kind:  model.KindCall,
 					Line:  148,
 				}: {},
 				{
 					Ident: "path/filepath.WalkFunc",
+					Kind:  model.KindType,
 					Line:  49,
 				}: {},
 				{
 					Ident: "path/filepath.WalkFunc",
+					Kind:  model.KindType,
 					Line:  147,
 				}: {},
 				{
 					Ident: "os.DirEntry",
+					Kind:  model.KindType,
 					Line:  48,
 				}: {},
 				{
 					Ident: "os.DirEntry",
+					Kind:  model.KindType,
 					Line:  142,
 				}: {},
 				{
 					Ident: "os.File",
+					Kind:  model.KindType,
 					Line:  153,
 				}: {},
 				{
 					Ident: "os.FileInfo",
+					Kind:  model.KindType,
 					Line:  36,
 				}: {},
 				{
 					Ident: "os.FileInfo",
+					Kind:  model.KindType,
 					Line:  78,
 				}: {},
 				{
 					Ident: "os.FileMode",
+					Kind:  model.KindType,
 					Line:  39,
 				}: {},
 				{
 					Ident: "os.FileMode",
+					Kind:  model.KindType,
 					Line:  103,
 				}: {},
 				{
 					Ident: "os.MkdirAll",
+					Kind:  model.KindCall,
 					Line:  104,
 				}: {},
 				{
 					Ident: "os.MkdirTemp",
+					Kind:  model.KindCall,
 					Line:  64,
 				}: {},
 				{
 					Ident: "os.MkdirTemp",
+					Kind:  model.KindCall,
 					Line:  129,
 				}: {},
 				{
 					Ident: "os.ReadDir",
+					Kind:  model.KindCall,
 					Line:  143,
 				}: {},
 				{
 					Ident: "os.ReadFile",
+					Kind:  model.KindCall,
 					Line:  124,
 				}: {},
 				{
 					Ident: "os.Remove",
+					Kind:  model.KindCall,
 					Line:  119,
 				}: {},
 				{
 					Ident: "os.RemoveAll",
+					Kind:  model.KindCall,
 					Line:  114,
 				}: {},
 				{
 					Ident: "os.Rename",
+					Kind:  model.KindCall,
 					Line:  99,
 				}: {},
 				{
 					Ident: "os.Stat",
+					Kind:  model.KindCall,
 					Line:  79,
 				}: {},
 				{
 					Ident: "strings.HasPrefix",
+					Kind:  model.KindCall,
 					Line:  93,
 				}: {},
 				{
 					Ident: "strings.HasPrefix",
+					Kind:  model.KindCall,
 					Line:  96,
 				}: {},
 				{
 					Ident: "time.Time",
+					Kind:  model.KindType,
 					Line:  40,
 				}: {},
 				{
 					Ident: "time.Time",
+					Kind:  model.KindType,
 					Line:  108,
 				}: {},
+				{
+					Ident: "os.File.Name",
+					Kind:  model.KindCall,
+					Line:  158,
+				}: {},
+				{
+					Ident: "os.File.Write",
+					Kind:  model.KindCall,
+					Line:  163,
+				}: {},
+				{
+					Ident: "os.File.Sync",
+					Kind:  model.KindCall,
+					Line:  168,
+				}: {},
+				{
+					Ident: "os.File.Close",
+					Kind:  model.KindCall,
+					Line:  173,
+				}: {},
 			},
 		},
 		{
@@ -203,30 +284,37 @@ func getTests(t *testing.T) []struct {
 			want: map[model.Locus]struct{}{
 				{
 					Ident: "runtime.GOOS",
+					Kind:  model.KindValue,
 					Line:  47,
 				}: {},
 				{
 					Ident: "runtime.GOOS",
+					Kind:  model.KindValue,
 					Line:  48,
 				}: {},
 				{
 					Ident: "sort.Sort",
+					Kind:  model.KindCall,
 					Line:  88,
 				}: {},
 				{
 					Ident: "sort.Sort",
+					Kind:  model.KindCall,
 					Line:  93,
 				}: {},
 				{
 					Ident: "sort.Sort",
+					Kind:  model.KindCall,
 					Line:  100,
 				}: {},
 				{
 					Ident: "sort.Sort",
+					Kind:  model.KindCall,
 					Line:  102,
 				}: {},
 				{
 					Ident: "strings.EqualFold",
+					Kind:  model.KindCall,
 					Line:  47,
 				}: {},
 			},
@@ -237,88 +325,274 @@ func getTests(t *testing.T) []struct {
 			want: map[model.Locus]struct{}{
 				{
 					Ident: "sync/atomic.Int32",
+					Kind:  model.KindType,
 					Line:  302,
 				}: {},
 				{
 					Ident: "sync/atomic.Value",
+					Kind:  model.KindType,
 					Line:  366,
 				}: {},
 				{
 					Ident: "errors.New",
+					Kind:  model.KindCall,
 					Line:  109,
 				}: {},
 				{
 					Ident: "sync.Mutex",
+					Kind:  model.KindType,
 					Line:  365,
 				}: {},
 				{
 					Ident: "sync.Once",
+					Kind:  model.KindType,
 					Line:  279,
 				}: {},
 				{
 					Ident: "time.AfterFunc",
-					Line:  579,
-				}: {},
-				{
-					Ident: "time.AfterFunc",
+					Kind:  model.KindCall,
 					Line:  579,
 				}: {},
 				{
 					Ident: "time.Duration",
+					Kind:  model.KindType,
 					Line:  630,
 				}: {},
 				{
 					Ident: "time.Duration",
+					Kind:  model.KindType,
 					Line:  637,
 				}: {},
 				{
 					Ident: "time.Now",
+					Kind:  model.KindCall,
 					Line:  631,
 				}: {},
 				{
 					Ident: "time.Now",
+					Kind:  model.KindCall,
 					Line:  638,
 				}: {},
 				{
 					Ident: "time.Time",
+					Kind:  model.KindType,
 					Line:  18,
 				}: {},
 				{
 					Ident: "time.Time",
+					Kind:  model.KindType,
 					Line:  125,
 				}: {},
 				{
 					Ident: "time.Time",
+					Kind:  model.KindType,
 					Line:  523,
 				}: {},
 				{
 					Ident: "time.Time",
+					Kind:  model.KindType,
 					Line:  552,
 				}: {},
 				{
 					Ident: "time.Time",
+					Kind:  model.KindType,
 					Line:  559,
 				}: {},
 				{
 					Ident: "time.Time",
+					Kind:  model.KindType,
 					Line:  593,
 				}: {},
 				{
 					Ident: "time.Time",
+					Kind:  model.KindType,
 					Line:  596,
 				}: {},
 				{
 					Ident: "time.Timer",
+					Kind:  model.KindType,
 					Line:  591,
 				}: {},
 				{
 					Ident: "time.Until",
+					Kind:  model.KindCall,
 					Line:  571,
 				}: {},
 				{
 					Ident: "time.Until",
+					Kind:  model.KindCall,
 					Line:  603,
 				}: {},
+				{
+					Ident: "sync.Mutex.Lock",
+					Kind:  model.KindCall,
+					Line:  231,
+				}: {},
+				{
+					Ident: "sync.Mutex.Unlock",
+					Kind:  model.KindCall,
+					Line:  232,
+				}: {},
+				{
+					Ident: "sync.Once.Do",
+					Kind:  model.KindCall,
+					Line:  263,
+				}: {},
+				{
+					Ident: "sync.Once.Do",
+					Kind:  model.KindCall,
+					Line:  288,
+				}: {},
+				{
+					Ident: "sync/atomic.Value.Load",
+					Kind:  model.KindCall,
+					Line:  322,
+				}: {},
+				{
+					Ident: "sync.Mutex.Lock",
+					Kind:  model.KindCall,
+					Line:  339,
+				}: {},
+				{
+					Ident: "sync.Mutex.Unlock",
+					Kind:  model.KindCall,
+					Line:  343,
+				}: {},
+				{
+					Ident: "sync/atomic.Value.Load",
+					Kind:  model.KindCall,
+					Line:  380,
+				}: {},
+				{
+					Ident: "sync.Mutex.Lock",
+					Kind:  model.KindCall,
+					Line:  384,
+				}: {},
+				{
+					Ident: "sync.Mutex.Unlock",
+					Kind:  model.KindCall,
+					Line:  385,
+				}: {},
+				{
+					Ident: "sync/atomic.Value.Load",
+					Kind:  model.KindCall,
+					Line:  386,
+				}: {},
+				{
+					Ident: "sync/atomic.Value.Store",
+					Kind:  model.KindCall,
+					Line:  389,
+				}: {},
+				{
+					Ident: "sync.Mutex.Lock",
+					Kind:  model.KindCall,
+					Line:  395,
+				}: {},
+				{
+					Ident: "sync.Mutex.Unlock",
+					Kind:  model.KindCall,
+					Line:  397,
+				}: {},
+				{
+					Ident: "sync.Mutex.Lock",
+					Kind:  model.KindCall,
+					Line:  421,
+				}: {},
+				{
+					Ident: "sync.Mutex.Unlock",
+					Kind:  model.KindCall,
+					Line:  431,
+				}: {},
+				{
+					Ident: "sync.Mutex.Lock",
+					Kind:  model.KindCall,
+					Line:  437,
+				}: {},
+				{
+					Ident: "sync.Mutex.Unlock",
+					Kind:  model.KindCall,
+					Line:  445,
+				}: {},
+				{
+					Ident: "sync/atomic.Int32.Add",
+					Kind:  model.KindCall,
+					Line:  449,
+				}: {},
+				{
+					Ident: "sync.Mutex.Lock",
+					Kind:  model.KindCall,
+					Line:  484,
+				}: {},
+				{
+					Ident: "sync.Mutex.Unlock",
+					Kind:  model.KindCall,
+					Line:  486,
+				}: {},
+				{
+					Ident: "sync/atomic.Value.Load",
+					Kind:  model.KindCall,
+					Line:  491,
+				}: {},
+				{
+					Ident: "sync/atomic.Value.Store",
+					Kind:  model.KindCall,
+					Line:  493,
+				}: {},
+				{
+					Ident: "sync.Mutex.Unlock",
+					Kind:  model.KindCall,
+					Line:  502,
+				}: {},
+				{
+					Ident: "time.Time.Before",
+					Kind:  model.KindCall,
+					Line:  563,
+				}: {},
+				{
+					Ident: "sync.Mutex.Lock",
+					Kind:  model.KindCall,
+					Line:  576,
+				}: {},
+				{
+					Ident: "sync.Mutex.Unlock",
+					Kind:  model.KindCall,
+					Line:  577,
+				}: {},
+				{
+					Ident: "time.Time.String",
+					Kind:  model.KindCall,
+					Line:  602,
+				}: {},
+				{
+					Ident: "time.Duration.String",
+					Kind:  model.KindCall,
+					Line:  603,
+				}: {},
+				{
+					Ident: "sync.Mutex.Lock",
+					Kind:  model.KindCall,
+					Line:  612,
+				}: {},
+				{
+					Ident: "time.Timer.Stop",
+					Kind:  model.KindCall,
+					Line:  614,
+				}: {},
+				{
+					Ident: "sync.Mutex.Unlock",
+					Kind:  model.KindCall,
+					Line:  617,
+				}: {},
+				{
+					Ident: "time.Time.Add",
+					Kind:  model.KindCall,
+					Line:  631,
+				}: {},
+				{
+					Ident: "time.Time.Add",
+					Kind:  model.KindCall,
+					Line:  638,
+				}: {},
 			},
 		},
 		{
@@ -327,12 +601,34 @@ func getTests(t *testing.T) []struct {
 			want: map[model.Locus]struct{}{
 				{
 					Ident: "testing.T",
+					Kind:  model.KindType,
 					Line:  12,
 				}: {},
 				{
 					Ident: "testing.T",
+					Kind:  model.KindType,
 					Line:  46,
 				}: {},
+				{
+					Ident: "testing.T.Fatalf",
+					Kind:  model.KindCall,
+					Line:  40,
+				}: {},
+				{
+					Ident: "testing.T.Fatalf",
+					Kind:  model.KindCall,
+					Line:  49,
+				}: {},
+				{
+					Ident: "strconv.FormatComplex",
+					Kind:  model.KindCall,
+					Line:  38,
+				}: {},
+				{
+					Ident: "strconv.FormatComplex",
+					Kind:  model.KindCall,
+					Line:  52,
+				}: {},
 			},
 		},
 		{
@@ -341,6 +637,7 @@ func getTests(t *testing.T) []struct {
 			want: map[model.Locus]struct{}{
 				{
 					Ident: "fmt.Print",
+					Kind:  model.KindValue,
 					Line:  9,
 				}: {},
 			},
@@ -351,12 +648,320 @@ func getTests(t *testing.T) []struct {
 			want: map[model.Locus]struct{}{
 				{
 					Ident: "reflect.TypeOf",
+					Kind:  model.KindCall,
+					Line:  364,
+				}: {},
+				{
+					Ident: "reflect.Type.PkgPath",
+					Kind:  model.KindCall,
 					Line:  364,
 				}: {},
 				{
 					Ident: "sync.Once",
+					Kind:  model.KindType,
 					Line:  287,
 				}: {},
+				{
+					Ident: "sync.Once.Do",
+					Kind:  model.KindCall,
+					Line:  292,
+				}: {},
+			},
+		},
+		{
+			name: "selections",
+			src:  openTest(t, "selections"),
+			want: map[model.Locus]struct{}{
+				{
+					Ident: "net/http.Client",
+					Kind:  model.KindEmbedded,
+					Line:  12,
+				}: {},
+				{
+					Ident: "bytes.Buffer",
+					Kind:  model.KindType,
+					Line:  14,
+				}: {},
+				{
+					Ident: "net/http.Client.Get",
+					Kind:  model.KindCall,
+					Line:  18,
+				}: {},
+				{
+					Ident: "io.ReadCloser.Close",
+					Kind:  model.KindCall,
+					Line:  22,
+				}: {},
+				{
+					Ident: "net/http.Response.Body",
+					Kind:  model.KindValue,
+					Line:  22,
+				}: {},
+				{
+					Ident: "bytes.Buffer.Reset",
+					Kind:  model.KindCall,
+					Line:  24,
+				}: {},
+				{
+					Ident: "io.Copy",
+					Kind:  model.KindCall,
+					Line:  25,
+				}: {},
+				{
+					Ident: "net/http.Response.Body",
+					Kind:  model.KindValue,
+					Line:  25,
+				}: {},
+				{
+					Ident: "bytes.Buffer.String",
+					Kind:  model.KindCall,
+					Line:  28,
+				}: {},
+				{
+					Ident: "bufio.ReadWriter",
+					Kind:  model.KindType,
+					Line:  31,
+				}: {},
+				{
+					Ident: "io.ReadCloser",
+					Kind:  model.KindType,
+					Line:  31,
+				}: {},
+				{
+					Ident: "strings.Builder",
+					Kind:  model.KindType,
+					Line:  32,
+				}: {},
+				{
+					Ident: "strings.Builder.WriteString",
+					Kind:  model.KindCall,
+					Line:  33,
+				}: {},
+				{
+					Ident: "bufio.Writer.WriteString",
+					Kind:  model.KindCall,
+					Line:  34,
+				}: {},
+				{
+					Ident: "strings.Builder.String",
+					Kind:  model.KindCall,
+					Line:  34,
+				}: {},
+				{
+					Ident: "bufio.Writer.Flush",
+					Kind:  model.KindCall,
+					Line:  37,
+				}: {},
+				{
+					Ident: "io.ReadCloser.Close",
+					Kind:  model.KindCall,
+					Line:  40,
+				}: {},
+			},
+		},
+		{
+			name: "imports",
+			src:  openTest(t, "imports"),
+			want: map[model.Locus]struct{}{
+				{
+					Ident: "math/rand/v2.Shuffle",
+					Kind:  model.KindCall,
+					Line:  11,
+				}: {},
+				{
+					Ident: "go/build/constraint.IsGoBuild",
+					Kind:  model.KindCall,
+					Line:  18,
+				}: {},
+				{
+					Ident: "strings.TrimSpace",
+					Kind:  model.KindCall,
+					Line:  18,
+				}: {},
+				{
+					Ident: "time.Time",
+					Kind:  model.KindType,
+					Line:  26,
+				}: {},
+				{
+					Ident: "time.Now",
+					Kind:  model.KindCall,
+					Line:  27,
+				}: {},
+			},
+		},
+		{
+			name: "dotimports",
+			src:  openTest(t, "dotimports"),
+			want: map[model.Locus]struct{}{
+				{
+					Ident: "embed",
+					Kind:  model.KindImport,
+					Line:  4,
+				}: {},
+				{
+					Ident: "net/http/pprof",
+					Kind:  model.KindImport,
+					Line:  7,
+				}: {},
+				{
+					Ident: "strings.HasPrefix",
+					Kind:  model.KindCall,
+					Line:  16,
+				}: {},
+				{
+					Ident: "strings.TrimPrefix",
+					Kind:  model.KindCall,
+					Line:  17,
+				}: {},
+				{
+					Ident: "os.File.WriteString",
+					Kind:  model.KindCall,
+					Line:  19,
+				}: {},
+				{
+					Ident: "os.Stdout",
+					Kind:  model.KindValue,
+					Line:  19,
+				}: {},
+				{
+					Ident: "log.Fatal",
+					Kind:  model.KindCall,
+					Line:  21,
+				}: {},
+				{
+					Ident: "net/http.ListenAndServe",
+					Kind:  model.KindCall,
+					Line:  21,
+				}: {},
+				{
+					Ident: "os.Getenv",
+					Kind:  model.KindCall,
+					Line:  21,
+				}: {},
+			},
+		},
+		{
+			name: "kinds",
+			src:  openTest(t, "kinds"),
+			want: map[model.Locus]struct{}{
+				{
+					Ident: "sync.Mutex",
+					Kind:  model.KindEmbedded,
+					Line:  14,
+				}: {},
+				{
+					Ident: "time.Duration",
+					Kind:  model.KindType,
+					Line:  16,
+				}: {},
+				{
+					Ident: "time.Duration",
+					Kind:  model.KindConversion,
+					Line:  20,
+				}: {},
+				{
+					Ident: "time.Second",
+					Kind:  model.KindValue,
+					Line:  20,
+				}: {},
+				{
+					Ident: "io.Writer",
+					Kind:  model.KindType,
+					Line:  23,
+				}: {},
+				{
+					Ident: "os.PathError",
+					Kind:  model.KindType,
+					Line:  24,
+				}: {},
+				{
+					Ident: "errors.As",
+					Kind:  model.KindCall,
+					Line:  25,
+				}: {},
+				{
+					Ident: "fmt.Fprintln",
+					Kind:  model.KindCall,
+					Line:  26,
+				}: {},
+				{
+					Ident: "io/fs.PathError.Path",
+					Kind:  model.KindValue,
+					Line:  26,
+				}: {},
+				{
+					Ident: "bytes.Buffer",
+					Kind:  model.KindType,
+					Line:  29,
+				}: {},
+				{
+					Ident: "bytes.Buffer.WriteString",
+					Kind:  model.KindMethodValue,
+					Line:  30,
+				}: {},
+				{
+					Ident: "os.Args",
+					Kind:  model.KindValue,
+					Line:  31,
+				}: {},
+				{
+					Ident: "bytes.Buffer",
+					Kind:  model.KindType,
+					Line:  32,
+				}: {},
+				{
+					Ident: "bytes.Buffer.Write",
+					Kind:  model.KindMethodValue,
+					Line:  32,
+				}: {},
+			},
+		},
+		{
+			name: "generics",
+			src:  openTest(t, "generics"),
+			want: map[model.Locus]struct{}{
+				{
+					Ident: "time.Duration",
+					Kind:  model.KindType,
+					Line:  12,
+				}: {},
+				{
+					Ident:    "sync/atomic.Pointer",
+					Kind:     model.KindType,
+					TypeArgs: "config",
+					Line:     15,
+				}: {},
+				{
+					Ident:    "sync.OnceValue",
+					Kind:     model.KindCall,
+					TypeArgs: "int",
+					Line:     17,
+				}: {},
+				{
+					Ident:    "slices.SortFunc",
+					Kind:     model.KindCall,
+					TypeArgs: "[]config, config",
+					Line:     22,
+				}: {},
+				{
+					Ident:    "cmp.Compare",
+					Kind:     model.KindCall,
+					TypeArgs: "time.Duration",
+					Line:     23,
+				}: {},
+				{
+					Ident:    "sync/atomic.Pointer.Store",
+					Kind:     model.KindCall,
+					TypeArgs: "config",
+					Line:     25,
+				}: {},
+				{
+					Ident:    "slices.Max",
+					Kind:     model.KindCall,
+					TypeArgs: "[]int, int",
+					Line:     29,
+				}: {},
 			},
 		},
 	}
@@ -377,3 +982,347 @@ func openTest(t *testing.T, name string) string {
 
 	return string(bs)
 }
+
+func TestExtractor_ExtractFiles(t *testing.T) {
+	ex := newPkgExtractor([]srcFile{
+		{name: "client.go", src: []byte(openTest(t, "pkg_client"))},
+		{name: "get.go", src: []byte(openTest(t, "pkg_get"))},
+	}, hostPlatform, nil)
+	if ex.Error != nil {
+		t.Fatal(ex.Error)
+	}
+
+	want := map[string]map[model.Locus]struct{}{
+		"client.go": {
+			{
+				Ident: "net/http.Client",
+				Kind:  model.KindEmbedded,
+				Line:  9,
+			}: {},
+			{
+				Ident: "time.Duration",
+				Kind:  model.KindType,
+				Line:  11,
+			}: {},
+			{
+				Ident: "net/http.Header",
+				Kind:  model.KindType,
+				Line:  14,
+			}: {},
+			{
+				Ident: "net/http.DefaultClient",
+				Kind:  model.KindValue,
+				Line:  20,
+			}: {},
+			{
+				Ident: "time.Second",
+				Kind:  model.KindValue,
+				Line:  21,
+			}: {},
+		},
+		"get.go": {
+			{
+				Ident: "net/http.MethodGet",
+				Kind:  model.KindValue,
+				Line:  9,
+			}: {},
+			{
+				Ident: "net/http.NewRequest",
+				Kind:  model.KindCall,
+				Line:  9,
+			}: {},
+			{
+				Ident: "net/http.Header.Set",
+				Kind:  model.KindCall,
+				Line:  13,
+			}: {},
+			{
+				Ident: "net/http.Request.Header",
+				Kind:  model.KindValue,
+				Line:  14,
+			}: {},
+			{
+				Ident: "net/http.Client.Do",
+				Kind:  model.KindCall,
+				Line:  16,
+			}: {},
+			{
+				Ident: "io.ReadCloser.Close",
+				Kind:  model.KindCall,
+				Line:  20,
+			}: {},
+			{
+				Ident: "net/http.Response.Body",
+				Kind:  model.KindValue,
+				Line:  20,
+			}: {},
+			{
+				Ident: "io.ReadAll",
+				Kind:  model.KindCall,
+				Line:  23,
+			}: {},
+			{
+				Ident: "net/http.Response.Body",
+				Kind:  model.KindValue,
+				Line:  23,
+			}: {},
+		},
+	}
+	got := ex.ExtractFiles()
+	for file, locus := range got {
+		got[file] = withoutSpans(locus)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extractor.ExtractFiles()\ngot 	= %v\nwant 	= %v", got, want)
+	}
+}
+
+func TestExtractor_Extract_spans(t *testing.T) {
+	ex := newExtractor([]byte(openTest(t, "spans")))
+	if ex.Error != nil {
+		t.Fatal(ex.Error)
+	}
+
+	want := map[model.Locus]struct{}{
+		{
+			Ident: "time.Time",
+			Kind:  model.KindType,
+			Line:  8,
+			Span: model.Span{
+				Start: model.Pos{Line: 8, Column: 23, Offset: 69},
+				End:   model.Pos{Line: 8, Column: 32, Offset: 78},
+			},
+			Snippet: model.Span{
+				Start: model.Pos{Line: 8, Column: 1, Offset: 47},
+				End:   model.Pos{Line: 10, Column: 2, Offset: 135},
+			},
+		}: {},
+		{
+			Ident: "time.Since",
+			Kind:  model.KindCall,
+			Line:  9,
+			Span: model.Span{
+				Start: model.Pos{Line: 9, Column: 9, Offset: 95},
+				End:   model.Pos{Line: 9, Column: 26, Offset: 112},
+			},
+			Snippet: model.Span{
+				Start: model.Pos{Line: 8, Column: 1, Offset: 47},
+				End:   model.Pos{Line: 10, Column: 2, Offset: 135},
+			},
+		}: {},
+		{
+			Ident: "time.Since",
+			Kind:  model.KindCall,
+			Line:  9,
+			Span: model.Span{
+				Start: model.Pos{Line: 9, Column: 31, Offset: 117},
+				End:   model.Pos{Line: 9, Column: 47, Offset: 133},
+			},
+			Snippet: model.Span{
+				Start: model.Pos{Line: 8, Column: 1, Offset: 47},
+				End:   model.Pos{Line: 10, Column: 2, Offset: 135},
+			},
+		}: {},
+		{
+			Ident: "net/http.Client",
+			Kind:  model.KindType,
+			Line:  12,
+			Span: model.Span{
+				Start: model.Pos{Line: 12, Column: 16, Offset: 152},
+				End:   model.Pos{Line: 12, Column: 27, Offset: 163},
+			},
+			Snippet: model.Span{
+				Start: model.Pos{Line: 12, Column: 1, Offset: 137},
+				End:   model.Pos{Line: 16, Column: 2, Offset: 220},
+			},
+		}: {},
+		{
+			Ident: "net/http.Client",
+			Kind:  model.KindComposite,
+			Line:  13,
+			Span: model.Span{
+				Start: model.Pos{Line: 13, Column: 10, Offset: 175},
+				End:   model.Pos{Line: 15, Column: 3, Offset: 218},
+			},
+			Snippet: model.Span{
+				Start: model.Pos{Line: 12, Column: 1, Offset: 137},
+				End:   model.Pos{Line: 16, Column: 2, Offset: 220},
+			},
+		}: {},
+		{
+			Ident: "time.Second",
+			Kind:  model.KindValue,
+			Line:  14,
+			Span: model.Span{
+				Start: model.Pos{Line: 14, Column: 16, Offset: 203},
+				End:   model.Pos{Line: 14, Column: 27, Offset: 214},
+			},
+			Snippet: model.Span{
+				Start: model.Pos{Line: 12, Column: 1, Offset: 137},
+				End:   model.Pos{Line: 16, Column: 2, Offset: 220},
+			},
+		}: {},
+	}
+	if got := ex.Extract(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Extractor.Extract()\ngot 	= %v\nwant 	= %v", got, want)
+	}
+}
+
+func TestExtractor_Extract_snippets(t *testing.T) {
+	ex := newExtractor([]byte(openTest(t, "snippets")))
+	if ex.Error != nil {
+		t.Fatal(ex.Error)
+	}
+
+	// Start and end lines of snippets, including doc comments
+	want := map[string][2]int{
+		"net/http/pprof": {3, 6},
+		"time.Second":    {8, 9},
+		"time.Sleep":     {11, 14},
+	}
+	got := make(map[string][2]int)
+	for l := range ex.Extract() {
+		got[l.Ident] = [2]int{l.Snippet.Start.Line, l.Snippet.End.Line}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extractor.Extract()\ngot \t= %v\nwant \t= %v", got, want)
+	}
+}
+
+// Spans and snippets are tested separately
+func withoutSpans(locus map[model.Locus]struct{}) map[model.Locus]struct{} {
+	ret := make(map[model.Locus]struct{})
+	for l := range locus {
+		l.Span = model.Span{}
+		l.Snippet = model.Span{}
+		ret[l] = struct{}{}
+	}
+	return ret
+}
+
+func TestExtractor_Extract_platform(t *testing.T) {
+	src := []srcFile{{name: "process_windows.go", src: []byte(openTest(t, "platform_windows"))}}
+
+	// Windows APIs aren't declared on Linux
+	if ex := newPkgExtractor(src, platform{"linux", "amd64"}, nil); len(ex.Diagnostics()) == 0 {
+		t.Error("want diagnostics")
+	}
+
+	ex := newPkgExtractor(src, platform{"windows", "amd64"}, nil)
+	if ex.Error != nil {
+		t.Fatal(ex.Error)
+	}
+	want := map[model.Locus]struct{}{
+		{
+			Ident: "syscall.Handle",
+			Kind:  model.KindType,
+			Line:  5,
+		}: {},
+		{
+			Ident: "syscall.GetCurrentProcess",
+			Kind:  model.KindCall,
+			Line:  6,
+		}: {},
+	}
+	if got := withoutSpans(ex.Extract()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Extractor.Extract()\ngot 	= %v\nwant 	= %v", got, want)
+	}
+}
+
+func TestExtractor_Extract_partial(t *testing.T) {
+	ex := newPkgExtractor([]srcFile{{name: "partial.go", src: []byte(openTest(t, "partial"))}}, hostPlatform, nil)
+	if ex.Error != nil {
+		t.Fatal(ex.Error)
+	}
+
+	want := map[model.Locus]struct{}{
+		{
+			Ident: "time.Second",
+			Kind:  model.KindValue,
+			Line:  11,
+		}: {},
+		{
+			Ident: "fmt.Errorf",
+			Kind:  model.KindCall,
+			Line:  13,
+		}: {},
+	}
+	if got := withoutSpans(ex.Extract()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Extractor.Extract()\ngot 	= %v\nwant 	= %v", got, want)
+	}
+
+	diagnostics := ex.Diagnostics()["partial.go"]
+	if want := []string{"7:2: could not import github.com/example/unknown (stubbed)"}; !reflect.DeepEqual(diagnostics, want) {
+		t.Errorf("Extractor.Diagnostics() = %v", diagnostics)
+	}
+}
+
+func TestExtractor_ExtractFiles_syntaxErrors(t *testing.T) {
+	ex := newPkgExtractor([]srcFile{
+		{name: "broken.go", src: []byte(openTest(t, "broken"))},
+		{name: "client.go", src: []byte(openTest(t, "pkg_client"))},
+	}, hostPlatform, nil)
+	if ex.Error != nil {
+		t.Fatal(ex.Error)
+	}
+	if len(ex.SyntaxErrors) != 1 {
+		t.Fatalf("Extractor.SyntaxErrors = %v", ex.SyntaxErrors)
+	}
+
+	locus := ex.ExtractFiles()
+	if _, ok := locus["broken.go"]; ok {
+		t.Errorf("Extractor.ExtractFiles() = %v, want: no locus of broken.go", locus)
+	}
+	want := model.Locus{
+		Ident: "net/http.Client",
+		Kind:  model.KindEmbedded,
+		Line:  9,
+	}
+	if _, ok := withoutSpans(locus["client.go"])[want]; !ok {
+		t.Errorf("Extractor.ExtractFiles() = %v, want: %v", locus["client.go"], want)
+	}
+}
+
+func TestExtractor_Extract_stubs(t *testing.T) {
+	ex := newPkgExtractor([]srcFile{{name: "stubs.go", src: []byte(openTest(t, "stubs"))}}, hostPlatform, nil)
+	if ex.Error != nil {
+		t.Fatal(ex.Error)
+	}
+
+	want := map[model.Locus]struct{}{
+		{
+			Ident: "os.ReadFile",
+			Kind:  model.KindCall,
+			Line:  12,
+		}: {},
+		{
+			Ident: "net/http.HandlerFunc",
+			Kind:  model.KindConversion,
+			Line:  21,
+		}: {},
+		{
+			Ident: "net/http.ResponseWriter",
+			Kind:  model.KindType,
+			Line:  21,
+		}: {},
+		{
+			Ident: "net/http.Request",
+			Kind:  model.KindType,
+			Line:  21,
+		}: {},
+	}
+	if got := withoutSpans(ex.Extract()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Extractor.Extract()\ngot \t= %v\nwant \t= %v", got, want)
+	}
+
+	// Selectors of stubbed packages aren't diagnosed
+	wantDiagnostics := map[string][]string{
+		"stubs.go": {
+			"7:2: could not import github.com/go-chi/chi/v5 (stubbed)",
+			"8:2: could not import gopkg.in/yaml.v3 (stubbed)",
+		},
+	}
+	if got := ex.Diagnostics(); !reflect.DeepEqual(got, wantDiagnostics) {
+		t.Errorf("Extractor.Diagnostics()\ngot \t= %v\nwant \t= %v", got, wantDiagnostics)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	filepat "path/filepath"
+	"regexp"
+	"strings"
+)
+
+// File names of common generators, e. g. "api.pb.go" (protoc-gen-go),
+// "api.pb.gw.go" (grpc-gateway) or "zz_generated.deepcopy.go"
+// (controller-gen)
+var generatedNames = regexp.MustCompile(`(\.pb(\.gw)?\.go|_generated\.go)$|^zz_generated\.`)
+
+// Header comments of generators not following the "Code generated ... DO NOT
+// EDIT." convention, e. g. "// Autogenerated by Thrift Compiler"
+var generatedComments = regexp.MustCompile(`(?i)^(auto-?generated|this file (was|is) (auto-?)?generated|generated by)\b`)
+
+// Reports whether the file is generated by its name or header comments, e. g.
+// "// Code generated by protoc-gen-go. DO NOT EDIT."
+func isGenerated(name string, src []byte) bool {
+	if generatedNames.MatchString(filepat.Base(name)) {
+		return true
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), name, src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return false
+	}
+	if ast.IsGenerated(file) {
+		return true
+	}
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+		for _, line := range strings.Split(group.Text(), "\n") {
+			if generatedComments.MatchString(line) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+func TestIsGenerated(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		src  string
+		want bool
+	}{
+		{
+			name: "protoc-gen-go",
+			file: "trf.pb.go",
+			src:  openTest(t, "trf.pb"),
+			want: true,
+		},
+		{
+			name: "code generated header",
+			file: "kind.go",
+			src:  "// Code generated by \"stringer -type=Kind\"; DO NOT EDIT.\n\npackage kind",
+			want: true,
+		},
+		{
+			name: "autogenerated header",
+			file: "service.go",
+			src:  "// Autogenerated by Thrift Compiler (0.13.0)\n// DO NOT EDIT UNLESS YOU ARE SURE THAT YOU KNOW WHAT YOU ARE DOING\n\npackage service",
+			want: true,
+		},
+		{
+			name: "controller-gen",
+			file: "zz_generated.deepcopy.go",
+			src:  "package v1",
+			want: true,
+		},
+		{
+			name: "header after package clause",
+			file: "main.go",
+			src:  "package main\n\n// Code generated by hand. DO NOT EDIT.",
+			want: false,
+		},
+		{
+			name: "handwritten",
+			file: "chtimes.go",
+			src:  openTest(t, "chtimes"),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isGenerated(tt.file, []byte(tt.src)); got != tt.want {
+				t.Errorf("isGenerated() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
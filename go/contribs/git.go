@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	filepat "path/filepath"
+	"strings"
+)
+
+// Finds the commit of a remote branch or tag without cloning, e. g.
+// "v2.40.0". Defaults to HEAD, e. g. the HEAD of the default branch of a
+// GitHub repository.
+func lsRemote(url, ref string) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	out, err := git("", "ls-remote", url, ref, ref+"^{}")
+	if err != nil {
+		return "", err
+	}
+
+	var commit string
+	for _, line := range strings.Split(out, "\n") {
+		sha, name, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		// Annotated tags point to tag objects, their commits are
+		// peeled, e. g. "refs/tags/v2.40.0^{}"
+		if strings.HasSuffix(name, "^{}") {
+			return sha, nil
+		}
+		if commit == "" {
+			commit = sha
+		}
+	}
+	if commit == "" {
+		return "", fmt.Errorf("can't find %s of %s", ref, url)
+	}
+	return commit, nil
+}
+
+// Finds the commit of HEAD of a cloned repository
+func headCommit(dir string) (string, error) {
+	return git(dir, "rev-parse", "HEAD")
+}
+
+// Finds files changed between the commit and HEAD of a shallow clone relative
+// to the repository, e. g. "cmd/gh/main.go". Fetches the commit if needed.
+// Fails if the commit isn't reachable anymore, e. g. after a force push.
+func changedFiles(dir, commit string) ([]string, error) {
+	if _, err := git(dir, "fetch", "-q", "--depth", "1", "--no-tags", "origin", commit); err != nil {
+		return nil, err
+	}
+	// Renames are deletions and additions
+	out, err := git(dir, "diff", "--name-status", "--no-renames", commit, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0)
+	for _, line := range strings.Split(out, "\n") {
+		_, file, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// Finds directories of changed files to re-extract, e. g. "/repo/cmd/gh" for
+// "cmd/gh/main.go". Packages are type-checked as a whole, so unchanged files
+// of the same directory are re-extracted as well. Reports false if changes
+// affect dependency graphs of modules, e. g. "go.mod" or vendored
+// dependencies, and the whole repository needs to be re-extracted.
+func changedDirs(repoDir string, files []string) (map[string]struct{}, bool) {
+	dirs := make(map[string]struct{})
+	for _, file := range files {
+		switch path.Base(file) {
+		case "go.mod", "go.sum", "go.work", "go.work.sum":
+			return nil, false
+		}
+		if file == "vendor" || strings.HasPrefix(file, "vendor/") || strings.Contains(file, "/vendor/") {
+			return nil, false
+		}
+		dirs[filepat.Join(repoDir, filepat.FromSlash(path.Dir(file)))] = struct{}{}
+	}
+	return dirs, true
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
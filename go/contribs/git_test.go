@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	filepat "path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestChangedFiles(t *testing.T) {
+	var (
+		remote = t.TempDir()
+		clone  = filepat.Join(t.TempDir(), "clone")
+	)
+	commit := func(files map[string]string) string {
+		for name, src := range files {
+			file := filepat.Join(remote, name)
+			if src == "" {
+				if err := os.Remove(file); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			if err := os.MkdirAll(filepat.Dir(file), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, args := range [][]string{
+			{"add", "-A"},
+			{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "test"},
+		} {
+			if _, err := git(remote, args...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		commit, err := headCommit(remote)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return commit
+	}
+
+	if _, err := git(remote, "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	indexed := commit(map[string]string{
+		"main.go":      "package main\n",
+		"cmd/a/a.go":   "package a\n",
+		"cmd/b/b.go":   "package b\n",
+		"cmd/c/c.go":   "package c\n",
+		"docs/READ.md": "# Docs\n",
+	})
+	head := commit(map[string]string{
+		"cmd/a/a.go": "package a\n\nfunc A() {}\n",
+		"cmd/b/b.go": "",
+		"cmd/d/d.go": "package d\n",
+	})
+
+	if _, err := git("", "clone", "-q", "--depth", "1", "--no-tags", "file://"+remote, clone); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git(remote, "-c", "user.name=test", "-c", "user.email=test@example.com", "tag", "-a", "-m", "test", "v1.0.0", indexed); err != nil {
+		t.Fatal(err)
+	}
+	for ref, want := range map[string]string{
+		"":       head,
+		"v1.0.0": indexed,
+	} {
+		got, err := lsRemote("file://"+remote, ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("lsRemote(%q) = %s, want: %s", ref, got, want)
+		}
+	}
+	if _, err := lsRemote("file://"+remote, "v2.0.0"); err == nil {
+		t.Fatal("want error for unknown ref")
+	}
+
+	files, err := changedFiles(clone, indexed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(files)
+	if want := []string{"cmd/a/a.go", "cmd/b/b.go", "cmd/d/d.go"}; !reflect.DeepEqual(files, want) {
+		t.Fatalf("changedFiles() = %v, want: %v", files, want)
+	}
+
+	if _, err := changedFiles(clone, "0123456789abcdef0123456789abcdef01234567"); err == nil {
+		t.Fatal("want error for unknown commit")
+	}
+}
+
+func TestChangedDirs(t *testing.T) {
+	const repoDir = "/tmp/repo"
+
+	tests := []struct {
+		name  string
+		files []string
+		want  map[string]struct{}
+		ok    bool
+	}{
+		{
+			name:  "packages",
+			files: []string{"main.go", "cmd/gh/main.go", "cmd/gh/root.go", "README.md"},
+			want: map[string]struct{}{
+				"/tmp/repo":        {},
+				"/tmp/repo/cmd/gh": {},
+			},
+			ok: true,
+		},
+		{
+			name:  "go.mod",
+			files: []string{"cmd/gh/main.go", "go.mod"},
+		},
+		{
+			name:  "nested go.sum",
+			files: []string{"tools/go.sum"},
+		},
+		{
+			name:  "vendor",
+			files: []string{"vendor/github.com/google/uuid/uuid.go"},
+		},
+		{
+			name:  "nested vendor",
+			files: []string{"tools/vendor/modules.txt"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := changedDirs(repoDir, test.files)
+			if ok != test.ok {
+				t.Fatalf("changedDirs() ok = %t, want: %t", ok, test.ok)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("changedDirs() = %v, want: %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestContribDir(t *testing.T) {
+	for dir, want := range map[string]string{
+		"/tmp/repo":        "/",
+		"/tmp/repo/cmd/gh": "/cmd/gh",
+	} {
+		if got := contribDir("/tmp/repo", dir); got != want {
+			t.Errorf("contribDir(%s) = %s, want: %s", dir, got, want)
+		}
+	}
+}
@@ -9,9 +9,8 @@ import (
 	"golang.org/x/oauth2"
 )
 
-var ghclient *github.Client
-
-func init() {
+// Creates a GitHub client, only needed to extract GitHub repositories
+func newGHClient() *github.Client {
 	accessTok := os.Getenv("GITHUB_ACCESS_TOKEN_CONTRIBS")
 	if accessTok == "" {
 		fmt.Fprintln(os.Stderr, "can't find Github access token")
@@ -20,5 +19,5 @@ func init() {
 		&oauth2.Token{AccessToken: accessTok},
 	)
 	httpClient := oauth2.NewClient(context.TODO(), tokSrc)
-	ghclient = github.NewClient(httpClient)
+	return github.NewClient(httpClient)
 }
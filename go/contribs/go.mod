@@ -6,7 +6,9 @@ require (
 	apis-go v0.0.0
 	github.com/google/go-github v17.0.0+incompatible
 	go.mongodb.org/mongo-driver v1.17.6
+	golang.org/x/mod v0.29.0
 	golang.org/x/oauth2 v0.32.0
+	golang.org/x/tools v0.38.0
 	mongo v0.0.0
 )
 
@@ -24,8 +26,6 @@ require (
 	github.com/xdg-go/stringprep v1.0.4 // indirect
 	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78 // indirect
 	golang.org/x/crypto v0.43.0 // indirect
-	golang.org/x/mod v0.29.0 // indirect
 	golang.org/x/sync v0.17.0 // indirect
 	golang.org/x/text v0.30.0 // indirect
-	golang.org/x/tools v0.38.0 // indirect
 )
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go/scanner"
+	"go/token"
+)
+
+// Hashes the code, e. g. to store identical files once
+func hashCode(src []byte) string {
+	sum := sha256.Sum256(src)
+	return hex.EncodeToString(sum[:])
+}
+
+// Hashes the tokens of the code ignoring comments and formatting, e. g. to
+// detect copies of a file with a different license header
+func hashTokens(src []byte) string {
+	var (
+		fset = token.NewFileSet()
+		file = fset.AddFile("", fset.Base(), len(src))
+		hash = sha256.New()
+
+		s scanner.Scanner
+	)
+	// Comments are skipped, errors are ignored
+	s.Init(file, src, nil, 0)
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		// Automatically inserted semicolons depend on formatting
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+
+		if lit == "" {
+			lit = tok.String()
+		}
+		hash.Write([]byte(lit))
+		// Separates tokens, e. g. "a b" from "ab"
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
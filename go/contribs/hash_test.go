@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestHashTokens(t *testing.T) {
+	const src = "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+	tests := []struct {
+		name  string
+		src   string
+		equal bool
+	}{
+		{
+			name:  "comments",
+			src:   "// Copyright 2024 Authors\n\npackage main\n\n// Greets\nfunc main() {\n\tprintln(\"hi\") // hi\n}\n",
+			equal: true,
+		},
+		{
+			name:  "formatting",
+			src:   "package main\nfunc main() { println(\"hi\") }",
+			equal: true,
+		},
+		{
+			name:  "string literal",
+			src:   "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+			equal: false,
+		},
+		{
+			name:  "identifier",
+			src:   "package main\n\nfunc main() {\n\tprint(\"hi\")\n}\n",
+			equal: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if equal := hashTokens([]byte(tt.src)) == hashTokens([]byte(src)); equal != tt.equal {
+				t.Errorf("hashTokens() equal = %v, want %v", equal, tt.equal)
+			}
+			if hashCode([]byte(tt.src)) == hashCode([]byte(src)) {
+				t.Error("hashCode() equal")
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	filepat "path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Imports packages for a single type-check. Packages of the module's
+// dependency graph take precedence. Otherwise, standard library packages are
+// shared by all importers of the same platform and other packages are
+// type-checked from source per importer. Standard library packages missing
+// from a loaded graph are type-checked from source per importer as well, so
+// their imports are those of the graph. Unresolved packages outside of the
+// standard library are stubbed.
+type srcImporter struct {
+	std      *stdImporter
+	fset     *token.FileSet
+	mod      *module
+	platform platform
+	// Import path of the package tested by an external test package, e. g.
+	// "net/url" of "net/url_test"
+	forTest string
+
+	pkgs map[string]*types.Package
+	// Placeholder packages without declarations by import path
+	stubs map[string]*types.Package
+}
+
+// The module is optional
+func newSrcImporter(fset *token.FileSet, platform platform, mod *module) *srcImporter {
+	return &srcImporter{
+		std:      getStdImporter(platform),
+		fset:     fset,
+		mod:      mod,
+		platform: platform,
+		pkgs:     make(map[string]*types.Package),
+		stubs:    make(map[string]*types.Package),
+	}
+}
+
+func (imp *srcImporter) Import(path string) (*types.Package, error) {
+	return imp.ImportFrom(path, ".", 0)
+}
+
+func (imp *srcImporter) ImportFrom(path, dir string, _ types.ImportMode) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	if imp.mod != nil {
+		if pkg, ok := imp.mod.lookup(imp.platform, imp.forTest, path); ok {
+			return pkg, nil
+		}
+	}
+	if pkg, ok := imp.stubs[path]; ok {
+		return pkg, nil
+	}
+
+	pkg, err := imp.importSrc(path, dir)
+	if err != nil && !isStdPath(path) {
+		return imp.stub(path), nil
+	}
+	return pkg, err
+}
+
+func (imp *srcImporter) importSrc(path, dir string) (*types.Package, error) {
+	bp, err := imp.std.ctxt.Import(path, dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	// Shared packages are distinct from those of the graph, e. g. "io.Reader"
+	// of both wouldn't be identical
+	if bp.Goroot && (imp.mod == nil || !imp.mod.loaded(imp.platform)) {
+		return imp.std.importPkg(bp)
+	}
+
+	if pkg, ok := imp.pkgs[bp.ImportPath]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through package %q", bp.ImportPath)
+		}
+		return pkg, nil
+	}
+	// Mark as in progress to detect import cycles
+	imp.pkgs[bp.ImportPath] = nil
+
+	pkg, err := checkPkg(bp, imp.fset, imp, imp.std.sizes)
+	if err != nil {
+		delete(imp.pkgs, bp.ImportPath)
+		return nil, err
+	}
+	imp.pkgs[bp.ImportPath] = pkg
+	return pkg, nil
+}
+
+// Creates a placeholder package without declarations. Selectors of the
+// package, e. g. "yaml.Unmarshal", are invalid, but don't abort the
+// type-check.
+func (imp *srcImporter) stub(path string) *types.Package {
+	pkg := types.NewPackage(path, guessPkgName(path))
+	pkg.MarkComplete()
+	imp.stubs[path] = pkg
+	return pkg
+}
+
+// Guesses the package name from the import path, e. g. "yaml" for
+// "gopkg.in/yaml.v3", "chi" for "github.com/go-chi/chi/v5" or "isatty" for
+// "github.com/mattn/go-isatty"
+func guessPkgName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	// Major version suffix, e. g. "/v5"
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	name, _, _ = strings.Cut(name, ".")
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimSuffix(name, "-go")
+	return strings.Map(func(r rune) rune {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, name)
+}
+
+// Standard library import paths have no dot in the first element, e. g.
+// "net/http" as opposed to "github.com/google/uuid"
+func isStdPath(path string) bool {
+	elem, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(elem, ".")
+}
+
+// Standard library importers by platform
+var (
+	stdImporters   = make(map[platform]*stdImporter)
+	stdImportersMu sync.Mutex
+)
+
+// Type-checks standard library packages from source once per platform, e. g.
+// "net/http" for "linux/amd64". Safe for concurrent use.
+type stdImporter struct {
+	ctxt  build.Context
+	fset  *token.FileSet
+	sizes types.Sizes
+
+	mu   sync.Mutex
+	pkgs map[string]*stdPkg
+}
+
+// Standard library package, type-checked or being type-checked
+type stdPkg struct {
+	done chan struct{}
+
+	pkg *types.Package
+	err error
+}
+
+func getStdImporter(platform platform) *stdImporter {
+	stdImportersMu.Lock()
+	defer stdImportersMu.Unlock()
+
+	imp, ok := stdImporters[platform]
+	if !ok {
+		imp = &stdImporter{
+			ctxt:  platform.context(),
+			fset:  token.NewFileSet(),
+			sizes: types.SizesFor("gc", platform.goarch),
+			pkgs:  make(map[string]*stdPkg),
+		}
+		stdImporters[platform] = imp
+	}
+	return imp
+}
+
+func (imp *stdImporter) Import(path string) (*types.Package, error) {
+	return imp.ImportFrom(path, ".", 0)
+}
+
+func (imp *stdImporter) ImportFrom(path, dir string, _ types.ImportMode) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+
+	bp, err := imp.ctxt.Import(path, dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	return imp.importPkg(bp)
+}
+
+// Type-checks the package or waits for it being type-checked. The standard
+// library has no import cycles.
+func (imp *stdImporter) importPkg(bp *build.Package) (*types.Package, error) {
+	imp.mu.Lock()
+	pkg, ok := imp.pkgs[bp.ImportPath]
+	if ok {
+		imp.mu.Unlock()
+		<-pkg.done
+		return pkg.pkg, pkg.err
+	}
+	pkg = &stdPkg{done: make(chan struct{})}
+	imp.pkgs[bp.ImportPath] = pkg
+	imp.mu.Unlock()
+
+	defer close(pkg.done)
+	// Waiting importers fail instead of deadlocking if type-checking panics
+	pkg.err = fmt.Errorf("can't type-check %s", bp.ImportPath)
+	pkg.pkg, pkg.err = checkPkg(bp, imp.fset, imp, imp.sizes)
+	return pkg.pkg, pkg.err
+}
+
+// Type-checks declarations of an imported package
+func checkPkg(bp *build.Package, fset *token.FileSet, importer types.Importer, sizes types.Sizes) (*types.Package, error) {
+	files := make([]*ast.File, 0, len(bp.GoFiles))
+	for _, name := range bp.GoFiles {
+		file, err := parser.ParseFile(fset, filepat.Join(bp.Dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	var firstErr error
+	conf := types.Config{
+		Importer: importer,
+		Sizes:    sizes,
+		// Only declarations are needed to import
+		IgnoreFuncBodies: true,
+		Error: func(err error) {
+			if firstErr == nil {
+				firstErr = err
+			}
+		},
+	}
+	pkg, _ := conf.Check(bp.ImportPath, fset, files, nil)
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return pkg, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"go/token"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSrcImporter_Import(t *testing.T) {
+	const path = "net/http"
+
+	var (
+		pkgs = make(chan any, 4)
+		wg   sync.WaitGroup
+	)
+	for range cap(pkgs) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			pkg, err := newSrcImporter(token.NewFileSet(), hostPlatform, nil).Import(path)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			pkgs <- pkg
+		}()
+	}
+	wg.Wait()
+	close(pkgs)
+
+	// Standard library packages are shared
+	var want any
+	for pkg := range pkgs {
+		if want == nil {
+			want = pkg
+		}
+		if pkg != want {
+			t.Errorf("srcImporter.Import(%q) isn't shared", path)
+		}
+	}
+}
+
+func TestGuessPkgName(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"github.com/google/uuid", "uuid"},
+		{"github.com/go-chi/chi/v5", "chi"},
+		{"gopkg.in/yaml.v3", "yaml"},
+		{"github.com/mattn/go-isatty", "isatty"},
+		{"github.com/opentracing/opentracing-go", "opentracing"},
+		{"k8s.io/client-go/kubernetes", "kubernetes"},
+		{"github.com/prometheus/client_golang/prometheus", "prometheus"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := guessPkgName(tt.path); got != tt.want {
+				t.Errorf("guessPkgName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStdImporter_importPkg_panic(t *testing.T) {
+	// File sets are missing, so type-checking panics
+	imp := &stdImporter{
+		ctxt: hostPlatform.context(),
+		pkgs: make(map[string]*stdPkg),
+	}
+	bp, err := imp.ctxt.Import("errors", ".", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatal("stdImporter.importPkg() didn't panic")
+			}
+		}()
+		_, _ = imp.importPkg(bp)
+	}()
+
+	errs := make(chan error)
+	go func() {
+		_, err := imp.importPkg(bp)
+		errs <- err
+	}()
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Fatal("stdImporter.importPkg() = nil error, want: error")
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatal("stdImporter.importPkg() deadlocked")
+	}
+}
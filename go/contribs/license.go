@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"go/parser"
+	"go/token"
+	"os"
+	filepat "path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Rule classifying license texts by phrases of normalized text, e. g. "mit
+// license" for "MIT License"
+type licenseRule struct {
+	spdx string
+	// Names, e. g. GitHub's "Apache License 2.0"
+	names []string
+	// All phrases must occur. The first phrase locates the license in texts
+	// of several licenses.
+	phrases []string
+	// No phrase must occur, e. g. of a similar license
+	not []string
+}
+
+// Rules of common licenses. Versions of the GPL family are told apart by
+// their headers.
+var licenseRules = []licenseRule{
+	{
+		spdx:    "Apache-2.0",
+		names:   []string{"Apache License 2.0"},
+		phrases: []string{"apache license version 2 0"},
+	},
+	{
+		spdx:  "MIT",
+		names: []string{"MIT License"},
+		phrases: []string{
+			"permission is hereby granted free of charge to any person obtaining a copy",
+			"the above copyright notice and this permission notice shall be included",
+		},
+	},
+	{
+		spdx:  "BSD-3-Clause",
+		names: []string{`BSD 3-Clause "New" or "Revised" License`},
+		phrases: []string{
+			"redistribution and use in source and binary forms with or without modification are permitted",
+			"neither the name",
+		},
+		not: []string{"all advertising materials"},
+	},
+	{
+		spdx:    "BSD-2-Clause",
+		names:   []string{`BSD 2-Clause "Simplified" License`},
+		phrases: []string{"redistribution and use in source and binary forms with or without modification are permitted"},
+		not:     []string{"neither the name", "all advertising materials"},
+	},
+	{
+		spdx:    "MPL-2.0",
+		names:   []string{"Mozilla Public License 2.0"},
+		phrases: []string{"mozilla public license version 2 0"},
+	},
+	{
+		spdx:    "ISC",
+		names:   []string{"ISC License"},
+		phrases: []string{"permission to use copy modify and or distribute this software for any purpose with or without fee is hereby granted"},
+	},
+	{
+		spdx:  "Zlib",
+		names: []string{"zlib License"},
+		phrases: []string{
+			"this software is provided as is without any express or implied warranty",
+			"altered source versions must be plainly marked as such",
+		},
+	},
+	{
+		spdx:    "AGPL-3.0",
+		names:   []string{"GNU Affero General Public License v3.0"},
+		phrases: []string{"gnu affero general public license version 3 19 november 2007"},
+	},
+	{
+		spdx:    "GPL-3.0",
+		names:   []string{"GNU General Public License v3.0"},
+		phrases: []string{"gnu general public license version 3 29 june 2007"},
+	},
+	{
+		spdx:    "GPL-2.0",
+		names:   []string{"GNU General Public License v2.0"},
+		phrases: []string{"gnu general public license version 2 june 1991"},
+	},
+	{
+		spdx:    "LGPL-3.0",
+		names:   []string{"GNU Lesser General Public License v3.0"},
+		phrases: []string{"gnu lesser general public license version 3 29 june 2007"},
+	},
+	{
+		spdx:    "LGPL-2.1",
+		names:   []string{"GNU Lesser General Public License v2.1"},
+		phrases: []string{"gnu lesser general public license version 2 1 february 1999"},
+	},
+	{
+		spdx:    "BSL-1.0",
+		names:   []string{"Boost Software License 1.0"},
+		phrases: []string{"boost software license version 1 0"},
+	},
+	{
+		spdx:    "Unlicense",
+		names:   []string{"The Unlicense"},
+		phrases: []string{"this is free and unencumbered software released into the public domain"},
+	},
+	{
+		spdx:    "CC0-1.0",
+		names:   []string{"Creative Commons Zero v1.0 Universal"},
+		phrases: []string{"cc0 1 0 universal"},
+	},
+}
+
+var (
+	// License files, e. g. "LICENSE", "LICENSE.md", "LICENSE-MIT" or "COPYING"
+	licenseFileNames = regexp.MustCompile(`(?i)^(licen[cs]e|copying)([.-].*)?$`)
+	// Copyright notices, e. g. "Copyright (c) 2019-2023 GitHub Inc."
+	copyrightLines = regexp.MustCompile(`(?i)^\W*copyright\b(\s*(\(c\)|©))?[\s,]*((\d{4}(\s*[-–,]\s*(\d{4}|present))*)[\s,]*)?(.*)$`)
+	// Trailing parts of copyright holders, e. g. "All rights reserved."
+	copyrightSuffixes = regexp.MustCompile(`(?i)[\s,;]*(all rights reserved\.?)?[\s,;]*$`)
+	// E-mail addresses and URLs, e. g. "<https://fsf.org/>"
+	copyrightContacts = regexp.MustCompile(`\s*<[^>]*>`)
+	// SPDX tags of files, e. g. "SPDX-License-Identifier: Apache-2.0 OR MIT"
+	spdxTags = regexp.MustCompile(`(?m)SPDX-License-Identifier:[ \t]*(.*?)[ \t]*(\*/)?[ \t]*$`)
+)
+
+// License of a repository detected from its license files
+type repoLicense struct {
+	// SPDX expression, e. g. "MIT" or "Apache-2.0 AND MIT" for several license
+	// files. Repositories are under all of their licenses. Empty if unknown.
+	spdx string
+	// Copyright holders, e. g. "GitHub Inc."
+	copyrights []string
+}
+
+// Detects the license of a repository from license files in its root
+// directory, e. g. "LICENSE". License files come first, then "COPYING" and
+// others, e. g. "LICENSE-MIT".
+func detectRepoLicense(repoDir string) (repoLicense, error) {
+	dirEntries, err := os.ReadDir(repoDir)
+	if err != nil {
+		return repoLicense{}, err
+	}
+	names := make([]string, 0)
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() && licenseFileNames.MatchString(dirEntry.Name()) {
+			names = append(names, dirEntry.Name())
+		}
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if pa, pb := licenseFilePriority(a), licenseFilePriority(b); pa != pb {
+			return pa - pb
+		}
+		return strings.Compare(a, b)
+	})
+
+	var (
+		lic  repoLicense
+		ids  = make([]string, 0)
+		seen = make(map[string]struct{})
+	)
+	for _, name := range names {
+		text, err := os.ReadFile(filepat.Join(repoDir, name))
+		if err != nil {
+			return repoLicense{}, err
+		}
+		if id := detectLicense(text); id != "" && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+		for _, holder := range findCopyrights(text) {
+			if _, ok := seen[holder]; ok {
+				continue
+			}
+			seen[holder] = struct{}{}
+			lic.copyrights = append(lic.copyrights, holder)
+		}
+	}
+	lic.spdx = strings.Join(ids, " AND ")
+	return lic, nil
+}
+
+func licenseFilePriority(name string) int {
+	base := strings.ToUpper(strings.TrimSuffix(name, filepat.Ext(name)))
+	switch base {
+	case "LICENSE", "LICENCE":
+		return 0
+
+	case "COPYING":
+		return 1
+	}
+	return 2
+}
+
+// Classifies a license text by its SPDX identifier, e. g. "MIT". Returns an
+// empty string if unknown. Of several licenses, the first one counts.
+func detectLicense(text []byte) string {
+	norm := normalizeLicense(string(text))
+
+	var (
+		id  string
+		pos = -1
+	)
+	for _, rule := range licenseRules {
+		i := strings.Index(norm, rule.phrases[0])
+		if i < 0 || (pos >= 0 && i >= pos) {
+			continue
+		}
+		if !containsAll(norm, rule.phrases[1:]) || containsAny(norm, rule.not) {
+			continue
+		}
+		id, pos = rule.spdx, i
+	}
+	return id
+}
+
+// Finds the SPDX expression and copyright holders of a file in comments
+// before its package clause, e. g. "// SPDX-License-Identifier: BSD-3-Clause"
+// and "// Copyright 2015 The Kubernetes Authors.". Vendored code may differ
+// from the repository's license.
+func findFileLicense(name string, src []byte) (string, []string) {
+	file, err := parser.ParseFile(token.NewFileSet(), name, src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return "", nil
+	}
+	var text strings.Builder
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+		text.WriteString(group.Text())
+	}
+
+	var spdx string
+	if m := spdxTags.FindStringSubmatch(text.String()); m != nil {
+		spdx = m[1]
+	}
+	copyrights := findCopyrights([]byte(text.String()))
+	if len(copyrights) == 0 {
+		copyrights = nil
+	}
+	return spdx, copyrights
+}
+
+// Finds the SPDX identifier of a license name, e. g. "Apache-2.0" for
+// "Apache license 2.0", "Apache-2.0 license" or "Apache License, Version 2.0"
+func findSPDX(name string) (string, bool) {
+	norm := normalizeLicenseName(name)
+	for _, rule := range licenseRules {
+		if normalizeLicenseName(rule.spdx) == norm {
+			return rule.spdx, true
+		}
+		for _, alias := range rule.names {
+			if normalizeLicenseName(alias) == norm {
+				return rule.spdx, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Reports whether the detected SPDX expression, e. g. "Apache-2.0 AND MIT",
+// doesn't contain the license name of the manifest, e. g. "MIT license".
+// Undetected licenses don't mismatch.
+func isLicenseMismatch(name, spdx string) bool {
+	if spdx == "" {
+		return false
+	}
+	id, ok := findSPDX(name)
+	return !ok || !slices.Contains(spdxIDs(spdx), id)
+}
+
+// Splits an SPDX expression into tokens, e. g. "(", "MIT", "OR",
+// "Apache-2.0", ")" for "(MIT OR Apache-2.0)"
+func spdxTokens(spdx string) []string {
+	return strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(spdx))
+}
+
+// Finds the license identifiers of an SPDX expression, e. g. "Apache-2.0" and
+// "MIT" for "Apache-2.0 AND (MIT OR BSD-3-Clause)". Exceptions, e. g.
+// "WITH LLVM-exception", aren't licenses.
+func spdxIDs(spdx string) []string {
+	ids := make([]string, 0)
+	tokens := spdxTokens(spdx)
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "(", ")", "AND", "OR":
+			continue
+
+		case "WITH":
+			i++
+			continue
+		}
+		ids = append(ids, tokens[i])
+	}
+	return ids
+}
+
+// Finds copyright holders of a license text, e. g. "GitHub Inc." for
+// "Copyright (c) 2019 GitHub Inc.". Copyright notices of license terms, e. g.
+// "the above copyright notice", placeholders, e. g. "[yyyy] [name of
+// copyright owner]", and the copyright of GPL texts aren't holders.
+func findCopyrights(text []byte) []string {
+	holders := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	for scanner.Scan() {
+		m := copyrightLines.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+		// Holders without notice, e. g. "Copyright notice", need a year
+		if m[2] == "" && m[4] == "" {
+			continue
+		}
+		holder := copyrightContacts.ReplaceAllString(m[7], "")
+		holder = copyrightSuffixes.ReplaceAllString(holder, "")
+		holder = strings.TrimSpace(strings.TrimPrefix(holder, "by "))
+		if holder == "" || strings.ContainsAny(holder, "[]{}<>") || strings.HasPrefix(holder, "Free Software Foundation") {
+			continue
+		}
+		if !slices.Contains(holders, holder) {
+			holders = append(holders, holder)
+		}
+	}
+	return holders
+}
+
+// Lower case words separated by single spaces, e. g. "version 2 0" for
+// "Version 2.0,"
+func normalizeLicense(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9')
+	}), " ")
+}
+
+// Normalizes license names ignoring filler words, e. g. "apache 2 0" for
+// "Apache License, Version 2.0" or "Apache-2.0 license"
+func normalizeLicenseName(name string) string {
+	words := make([]string, 0)
+	for _, word := range strings.Fields(normalizeLicense(name)) {
+		switch word {
+		case "the", "license", "licence", "version", "v":
+			continue
+		}
+		// "v3" of "v3.0"
+		if len(word) > 1 && word[0] == 'v' && '0' <= word[1] && word[1] <= '9' {
+			word = word[1:]
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " ")
+}
+
+func containsAll(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if !strings.Contains(s, substr) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAny(s string, substrs []string) bool {
+	return slices.ContainsFunc(substrs, func(substr string) bool {
+		return strings.Contains(s, substr)
+	})
+}
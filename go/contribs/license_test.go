@@ -0,0 +1,245 @@
+package main
+
+import (
+	"os"
+	filepat "path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	mitText = `MIT License
+
+Copyright (c) 2019 GitHub Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+`
+	bsd3Text = `Copyright (c) 2009 The Go Authors. All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are
+met:
+
+   * Redistributions of source code must retain the above copyright
+notice, this list of conditions and the following disclaimer.
+   * Neither the name of Google Inc. nor the names of its
+contributors may be used to endorse or promote products derived from
+this software without specific prior written permission.
+`
+	bsd2Text = `Copyright 2015-2023 Jane Doe <jane@example.com>
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice, this
+   list of conditions and the following disclaimer.
+`
+	apacheText = `
+                                 Apache License
+                           Version 2.0, January 2004
+                        http://www.apache.org/licenses/
+
+   APPENDIX: How to apply the Apache License to your work.
+
+      Copyright [yyyy] [name of copyright owner]
+
+   Copyright 2014 The Kubernetes Authors.
+`
+	gpl3Text = `                    GNU GENERAL PUBLIC LICENSE
+                       Version 3, 29 June 2007
+
+ Copyright (C) 2007 Free Software Foundation, Inc. <https://fsf.org/>
+
+library.  If this is what you want to do, use the GNU Lesser General
+Public License instead of this License.
+`
+)
+
+func TestDetectLicense(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"MIT", mitText, "MIT"},
+		{"BSD-3-Clause", bsd3Text, "BSD-3-Clause"},
+		{"BSD-2-Clause", bsd2Text, "BSD-2-Clause"},
+		{"Apache-2.0", apacheText, "Apache-2.0"},
+		{"GPL-3.0", gpl3Text, "GPL-3.0"},
+		{"notice", "Licensed under the Apache License, Version 2.0 (the \"License\");", "Apache-2.0"},
+		// Third-party licenses appended
+		{"first", apacheText + "\n" + mitText, "Apache-2.0"},
+		{"unknown", "All rights reserved.", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := detectLicense([]byte(test.text)); got != test.want {
+				t.Fatalf("detectLicense() = %q, want: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFindCopyrights(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"MIT", mitText, []string{"GitHub Inc."}},
+		{"BSD-3-Clause", bsd3Text, []string{"The Go Authors."}},
+		{"e-mail", bsd2Text, []string{"Jane Doe"}},
+		{"placeholder", apacheText, []string{"The Kubernetes Authors."}},
+		{"GPL", gpl3Text, []string{}},
+		{"symbol", "Copyright © 2021-present Acme Corp\ncopyright: see AUTHORS", []string{"Acme Corp"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := findCopyrights([]byte(test.text)); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("findCopyrights() = %q, want: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFindSPDX(t *testing.T) {
+	for name, want := range map[string]string{
+		"Apache license 2.0":                     "Apache-2.0",
+		"Apache-2.0 license":                     "Apache-2.0",
+		"Apache License, Version 2.0":            "Apache-2.0",
+		"BSD 3-Clause license":                   "BSD-3-Clause",
+		"GNU Affero general public license v3.0": "AGPL-3.0",
+		"MIT license":                            "MIT",
+		"Zlib license":                           "Zlib",
+	} {
+		if got, ok := findSPDX(name); !ok || got != want {
+			t.Errorf("findSPDX(%q) = %q, %t, want: %q", name, got, ok, want)
+		}
+	}
+	if _, ok := findSPDX("Proprietary"); ok {
+		t.Error("want no SPDX identifier of unknown license")
+	}
+
+	// Licenses of the manifest are known
+	man, err := loadManifestFile("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, repo := range man.Repos {
+		if _, ok := findSPDX(repo.License); !ok {
+			t.Errorf("%s/%s: unknown license %q", repo.Owner, repo.Name, repo.License)
+		}
+	}
+}
+
+func TestIsLicenseMismatch(t *testing.T) {
+	tests := []struct {
+		name, spdx string
+		want       bool
+	}{
+		{"MIT license", "MIT", false},
+		{"MIT license", "Apache-2.0 AND MIT", false},
+		{"MIT license", "Apache-2.0 OR MIT", false},
+		{"MIT license", "(Apache-2.0 WITH LLVM-exception) AND MIT", false},
+		{"MIT license", "Apache-2.0", true},
+		{"Proprietary", "MIT", true},
+		{"MIT license", "", false},
+	}
+	for _, test := range tests {
+		if got := isLicenseMismatch(test.name, test.spdx); got != test.want {
+			t.Errorf("isLicenseMismatch(%q, %q) = %t, want: %t", test.name, test.spdx, got, test.want)
+		}
+	}
+}
+
+func TestDetectRepoLicense(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"LICENSE-MIT":    mitText,
+		"LICENSE.apache": apacheText,
+		"README.md":      mitText,
+	} {
+		if err := os.WriteFile(filepat.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := detectRepoLicense(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := repoLicense{
+		spdx:       "Apache-2.0 AND MIT",
+		copyrights: []string{"The Kubernetes Authors.", "GitHub Inc."},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("detectRepoLicense() = %+v, want: %+v", got, want)
+	}
+}
+
+func TestFindFileLicense(t *testing.T) {
+	tests := []struct {
+		name       string
+		src        string
+		spdx       string
+		copyrights []string
+	}{
+		{
+			name: "SPDX",
+			src: `// Copyright 2015 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+`,
+			spdx:       "Apache-2.0",
+			copyrights: []string{"The Kubernetes Authors."},
+		},
+		{
+			name: "block comment",
+			src: `/* SPDX-License-Identifier: BSD-3-Clause OR MIT */
+
+// Copyright (c) 2009 The Go Authors. All rights reserved.
+
+// Package uuid generates UUIDs.
+package uuid
+`,
+			spdx:       "BSD-3-Clause OR MIT",
+			copyrights: []string{"The Go Authors."},
+		},
+		{
+			name: "after package clause",
+			src: `package main
+
+// SPDX-License-Identifier: MIT
+// Copyright 2020 Jane Doe
+`,
+		},
+		{
+			name: "none",
+			src:  "//go:build linux\n\npackage main\n",
+		},
+		{
+			name: "invalid",
+			src:  "// SPDX-License-Identifier: MIT\n\nfunc main() {}\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spdx, copyrights := findFileLicense("main.go", []byte(test.src))
+			if spdx != test.spdx {
+				t.Errorf("findFileLicense() SPDX = %q, want: %q", spdx, test.spdx)
+			}
+			if !reflect.DeepEqual(copyrights, test.copyrights) {
+				t.Errorf("findFileLicense() copyrights = %q, want: %q", copyrights, test.copyrights)
+			}
+		})
+	}
+}
@@ -2,20 +2,23 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"go/parser"
+	"go/token"
 	"io/fs"
 	"log"
 	"mongo"
 	"os"
 	"os/exec"
 	filepat "path/filepath"
+	"slices"
 	"sync"
 
 	goapis "apis-go/api"
 
 	"contribs-go/model"
 
-	"github.com/google/go-github/github"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -33,23 +36,50 @@ func init() {
 
 const workersn = 3
 
+// Re-extracts all repositories, e. g. after changes of the extractor.
+// Otherwise, unchanged repositories are skipped and only directories with
+// changed files and their importers are re-extracted.
+var full = flag.Bool("full", false, "re-extract all repositories")
+
+// Repositories to extract, e. g. "github" for GitHub repositories of
+// "repos.go" or "/src/monorepo" for a local directory
+var source = flag.String("source", sourceGitHub, `repositories to extract: "github", a directory, a bare git repository or a "file://" URL`)
+
+// Manifest of curated repositories, e. g. of a fork. Defaults to the embedded
+// "repos.json".
+var manifestFile = flag.String("manifest", "", "manifest of repositories, defaults to the embedded repos.json")
+
 func main() {
+	flag.Parse()
 	ctx := context.Background()
 
-	repos, err := findRepos(ctx, ghclient)
+	man, err := loadManifestFile(*manifestFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	repos, err := findSourceRepos(ctx, *source, man)
 	checkErr(err)
-	reposn := len(repos)
-	log.Printf("repos: %d", reposn)
+	log.Printf("repos: %d", len(repos))
 
 	var (
-		reposchan = make(chan *github.Repository)
+		reposchan = make(chan repository)
 		wg        sync.WaitGroup
 
 		contribsn, filesn int
 	)
 	defer func() {
-		checkErr(saveCatalogue(ctx, contribsn, reposn))
-		checkErr(insertLicenses(ctx))
+		// Contributions and repositories of earlier runs, e. g. unchanged or
+		// of other sources, count as well
+		total, err := countContribs(ctx)
+		checkErr(err)
+		totalRepos, err := countRepos(ctx)
+		checkErr(err)
+		checkErr(saveCatalogue(ctx, total, totalRepos))
+		// Licenses are those of the manifest's repositories
+		if *source == sourceGitHub {
+			checkErr(insertLicenses(ctx, man))
+		}
+		checkErr(deleteOrphanBlobs(ctx))
 	}()
 	for range workersn {
 		go worker(
@@ -72,17 +102,17 @@ func main() {
 
 func worker(
 	ctx context.Context,
-	repos <-chan *github.Repository,
+	repos <-chan repository,
 	wg *sync.WaitGroup,
 	contribsn,
 	filesn *int,
 ) {
-	f := func(repo *github.Repository) {
+	f := func(repo repository) {
 		wg.Add(1)
 		defer wg.Done()
 
-		repoOwner := repo.Owner.GetLogin()
-		repoName := repo.GetName()
+		repoOwner := repo.owner
+		repoName := repo.name
 
 		logger := log.New(
 			os.Stdout,
@@ -90,28 +120,151 @@ func worker(
 			log.Lmsgprefix,
 		)
 
-		repoDir, err := os.MkdirTemp("", fmt.Sprintf("%s_%s", repoOwner, repoName))
+		var (
+			repoDir string
+			commit  string
+			// Directories to re-extract, all if nil
+			dirs map[string]struct{}
+			// Indexed state, zero for directories extracted in place
+			state model.Repo
+		)
+		if repo.dir != "" {
+			// Directories are extracted in place and as a whole
+			repoDir = repo.dir
+			commit = repo.commit
+		} else {
+			var err error
+			state, err = findRepoState(ctx, repoOwner, repoName)
+			if err != nil {
+				logErr(logger, err)
+				return
+			}
+			indexed := state.Commit
+			if *full {
+				indexed = ""
+			}
+			// Changed globs and limits of the manifest apply to all files
+			if indexed != "" && state.Config != repo.config {
+				logger.Printf("manifest changed")
+				indexed = ""
+			}
+			// Changed policies, e. g. of the manifest, apply to unchanged
+			// repositories as well
+			if policy, _ := repo.decidePolicy(state.SPDX); indexed != "" && policy == state.Policy {
+				head, err := lsRemote(repo.cloneURL, repo.ref)
+				if err != nil {
+					logErr(logger, err)
+				} else if head == indexed {
+					logger.Printf("unchanged: %s", head)
+					return
+				}
+			}
+
+			repoDir, err = os.MkdirTemp("", fmt.Sprintf("%s_%s", repoOwner, repoName))
+			if err != nil {
+				logErr(logger, err)
+				return
+			}
+
+			logger.Printf("cloning: %s", repo.cloneURL)
+			args := []string{
+				"clone",
+				"-q",
+				"--depth", "1",
+				"--no-tags",
+				"--filter=blob:limit=75k",
+			}
+			if repo.ref != "" {
+				args = append(args, "--branch", repo.ref)
+			}
+			if err := exec.Command("git", append(args, repo.cloneURL, repoDir)...).Run(); err != nil {
+				logErr(logger, err)
+				logErr(logger, os.RemoveAll(repoDir))
+				return
+			}
+
+			commit, err = headCommit(repoDir)
+			if err != nil {
+				logErr(logger, err)
+				logErr(logger, os.RemoveAll(repoDir))
+				return
+			}
+
+			// Limits of files apply to the whole repository
+			if indexed != "" && repo.limits.MaxFiles == 0 {
+				files, err := changedFiles(repoDir, indexed)
+				if err != nil {
+					// Re-extract all, e. g. after a force push
+					logErr(logger, err)
+				} else if changed, ok := changedDirs(repoDir, files); ok {
+					logger.Printf("changed files: %d", len(files))
+					dirs = changed
+				} else {
+					logger.Printf("dependencies changed")
+				}
+			}
+
+			rmExtraneous(logger, repoDir)
+		}
+		logger.Printf("commit: %s", commit)
+
+		lic, err := detectRepoLicense(repoDir)
 		if err != nil {
 			logErr(logger, err)
-			return
+		}
+		logger.Printf("license: %s", lic.spdx)
+		policy, spdx := repo.decidePolicy(lic.spdx)
+		logger.Printf("policy: %s", policy)
+		// Code of all files is subject to the policy
+		if policy != state.Policy {
+			dirs = nil
+		}
+		saveState := func() {
+			// Directories extracted in place are always extracted as a whole
+			if repo.dir != "" {
+				return
+			}
+			checkErr(saveRepo(ctx, model.Repo{
+				ID:         repoOwner + "/" + repoName,
+				Commit:     commit,
+				SPDX:       lic.spdx,
+				Copyrights: lic.copyrights,
+				Policy:     policy,
+				Config:     repo.config,
+			}))
 		}
 
-		logger.Printf("cloning: %s", repo.GetCloneURL())
-		if err := exec.Command("git",
-			"clone",
-			"-q",
-			"--depth", "1",
-			"--no-tags",
-			"--filter=blob:limit=75k",
-			*repo.CloneURL,
-			repoDir,
-		).Run(); err != nil {
-			logErr(logger, err)
-			logErr(logger, os.RemoveAll(repoDir))
+		if policy == policyDeny {
+			_, err := mongoColl.DeleteMany(ctx, bson.M{
+				"repo_owner": repoOwner,
+				"repo_name":  repoName,
+			})
+			checkErr(err)
+			if repo.dir == "" {
+				checkErr(os.RemoveAll(repoDir))
+			}
+			saveState()
 			return
 		}
 
-		rmExtraneous(logger, repoDir)
+		// Modules resolve imports of their packages offline
+		mods := findModules(repoDir)
+		logger.Printf("modules: %d", len(mods))
+		// Locus of importing packages depends on changed packages, e. g. on
+		// their exported types
+		if dirs != nil {
+			dirs = findImporters(repoDir, mods, dirs)
+			logger.Printf("changed directories: %d", len(dirs))
+			// Graphs of unchanged packages aren't needed
+			for _, mod := range mods {
+				mod.dirs = make(map[string]struct{})
+			}
+			for dir := range dirs {
+				if mod := findModule(mods, dir); mod != nil {
+					mod.dirs[dir] = struct{}{}
+				}
+			}
+		}
 
 		var repofilesn int
 		go func() {
@@ -119,6 +272,9 @@ func worker(
 				if err != nil {
 					return err
 				}
+				if info.IsDir() && info.Name() == "vendor" {
+					return filepat.SkipDir
+				}
 				repofilesn++
 				return nil
 			})
@@ -133,118 +289,318 @@ func worker(
 			gofilesn, locusn int
 
 			contribs = make([]any, 0)
+			// Code by hash
+			blobs = make(map[string]string)
 		)
-		for file := range findGoFiles(repoDir) {
-			logger.Printf("file: %s", file)
-			gofilesn++
+		include := func(file string, size int64) bool {
+			return repo.includeFile(repoDir, file, size)
+		}
+		for pkg := range findGoPkgs(repoDir, dirs, include) {
+			// Remaining packages are drained
+			if repo.limits.MaxFiles > 0 && gofilesn >= repo.limits.MaxFiles {
+				continue
+			}
+
+			srcFiles := make([]srcFile, 0, len(pkg.files))
+			for _, file := range pkg.files {
+				// Files are contained in packages of several platforms
+				if _, ok := pkg.platforms[file]; ok {
+					logger.Printf("file: %s", file)
+					gofilesn++
+				}
 
-			fileBytes, err := os.ReadFile(file)
+				fileBytes, err := os.ReadFile(file)
+				if err != nil {
+					logErr(logger, err)
+					continue
+				}
+				srcFiles = append(srcFiles, srcFile{name: file, src: fileBytes})
+			}
+			mod := findModule(mods, filepat.Dir(pkg.files[0]))
+			pkgLocus, pkgDiagnostics, err := findLocus(logger, srcFiles, pkg.platform, mod)
 			if err != nil {
 				logErr(logger, err)
 				continue
 			}
-			locus, ok, err := findLocus(fileBytes)
-			if !ok {
-				logErr(logger, err)
-				continue
+
+			for _, srcFile := range srcFiles {
+				platforms, ok := pkg.platforms[srcFile.name]
+				if !ok {
+					continue
+				}
+				locus, ok := pkgLocus[srcFile.name]
+				if !ok {
+					continue
+				}
+				locusn += len(locus)
+				logger.Printf("locus: %d", len(locus))
+				diagnostics := pkgDiagnostics[srcFile.name]
+				if len(diagnostics) > 0 {
+					logger.Printf("diagnostics: %d", len(diagnostics))
+				}
+
+				category := findCategory(srcFile.name, srcFile.src)
+				generated := isGenerated(srcFile.name, srcFile.src)
+				scoreLocus(locus, srcFile.src, category, generated, repo.stars)
+
+				fileLicense, fileCopyrights := findFileLicense(srcFile.name, srcFile.src)
+
+				// Hashes are of stored code, so truncated copies aren't
+				// deduplicated with full copies
+				code := srcFile.src
+				if policy == policySnippet {
+					code = truncateToSnippets(code, locus)
+				}
+
+				pat := srcFile.name[len(repoDir):]
+				filepath := filepat.Dir(pat)
+				filename := filepat.Base(pat)
+				hash := hashCode(code)
+				contribs = append(contribs, model.Contrib{
+					Locus:          locus,
+					Category:       category,
+					Commit:         commit,
+					Diagnostics:    diagnostics,
+					Filepath:       filepath,
+					Filename:       filename,
+					FileCopyrights: fileCopyrights,
+					FileLicense:    fileLicense,
+					Hash:           hash,
+					IsGenerated:    generated,
+					License:        spdx,
+					Platforms:      platforms,
+					Policy:         policy,
+					RepoOwner:      repoOwner,
+					RepoName:       repoName,
+					RepoURL:        repo.url,
+					TokenHash:      hashTokens(code),
+				})
+				blobs[hash] = string(code)
+
+				mu.Lock()
+				*contribsn += 1
+				mu.Unlock()
 			}
-			locusn += len(locus)
-			logger.Printf("locus: %d", len(locus))
-
-			pat := file[len(repoDir):]
-			code := string(fileBytes)
-			filepath := filepat.Dir(pat)
-			filename := filepat.Base(pat)
-			contribs = append(contribs, model.Contrib{
-				Locus:     locus,
-				Code:      code,
-				Filepath:  filepath,
-				Filename:  filename,
-				RepoOwner: repoOwner,
-				RepoName:  repoName,
-			})
+		}
 
-			mu.Lock()
-			*contribsn += 1
-			mu.Unlock()
+		// Dependency graphs are loaded on demand
+		for _, mod := range mods {
+			for platform, err := range mod.errs {
+				logErr(logger, fmt.Errorf("%s (%s): %s", mod.dir[len(repoDir):], platform, err))
+			}
 		}
 
 		// Remove temporary repository directory
-		checkErr(os.RemoveAll(repoDir))
+		if repo.dir == "" {
+			checkErr(os.RemoveAll(repoDir))
+		}
 
 		logger.Printf("contribs: %d", len(contribs))
 		logger.Printf("locus: %d", locusn)
 		logger.Printf("files: %d", gofilesn)
 
-		if len(contribs) == 0 {
+		// Keep existing contributions if nothing could be extracted
+		if dirs == nil && len(contribs) == 0 {
 			return
 		}
 
-		// Delete existing contributions
-		_, err = mongoColl.DeleteMany(ctx, bson.M{
+		// Delete existing contributions of re-extracted directories
+		filter := bson.M{
 			"repo_owner": repoOwner,
 			"repo_name":  repoName,
-		})
+		}
+		if dirs != nil {
+			filepaths := make([]string, 0, len(dirs))
+			for dir := range dirs {
+				filepaths = append(filepaths, contribDir(repoDir, dir))
+			}
+			filter["filepath"] = bson.M{"$in": filepaths}
+		}
+		_, err = mongoColl.DeleteMany(ctx, filter)
 		checkErr(err)
 		// Save new contributions
-		_, err = mongoColl.InsertMany(ctx, contribs)
-		checkErr(err)
+		if len(contribs) > 0 {
+			_, err = mongoColl.InsertMany(ctx, contribs)
+			checkErr(err)
+			checkErr(insertBlobs(ctx, blobs))
+		}
+		saveState()
 	}
 	for repo := range repos {
 		f(repo)
 	}
 }
 
-func findLocus(src []byte) ([]model.Locus, bool, error) {
-	ex := newExtractor(src)
+// Finds locus and diagnostics of a package by file name. Type errors don't
+// prevent finding locus. Files with syntax errors are logged and skipped.
+func findLocus(logger *log.Logger, srcFiles []srcFile, platform platform, mod *module) (map[string][]model.Locus, map[string][]string, error) {
+	ex := newPkgExtractor(srcFiles, platform, mod)
 	if ex.Error != nil {
-		return []model.Locus{}, false, ex.Error
+		return map[string][]model.Locus{}, map[string][]string{}, ex.Error
+	}
+	for _, err := range ex.SyntaxErrors {
+		logErr(logger, err)
 	}
 
-	locus := ex.Extract()
+	locus := ex.ExtractFiles()
 	if ex.Error != nil {
-		return []model.Locus{}, false, ex.Error
+		return map[string][]model.Locus{}, map[string][]string{}, ex.Error
 	}
 
-	ret := make([]model.Locus, 0)
-	for api := range locus {
-		ret = append(ret, api)
+	ret := make(map[string][]model.Locus)
+	for file, apis := range locus {
+		for api := range apis {
+			ret[file] = append(ret[file], api)
+		}
 	}
-	return ret, len(ret) > 0, nil
+	return ret, ex.Diagnostics(), nil
 }
 
-func findGoFiles(dir string) chan string {
-	files := make(chan string, 100)
+// Go package type-checked for a platform
+type goPkg struct {
+	platform platform
+	files    []string
+	// Platforms of files first type-checked with this package, e. g.
+	// "linux/amd64". Files type-checked for an earlier platform aren't
+	// contained.
+	platforms map[string][]string
+}
+
+// Finds Go files grouped by package and platform. Files of the same
+// directory and package clause, including in-package tests, built for the
+// same platform form a package. External test packages ("_test") form their
+// own package. Files not built for any platform, e. g. because of custom
+// build tags, are packages on their own. Only packages of the directories are
+// found, all if nil. Files not included, e. g. by globs of the manifest, aren't
+// part of packages. All files are included if nil.
+func findGoPkgs(dir string, dirs map[string]struct{}, include func(file string, size int64) bool) chan goPkg {
+	pkgs := make(chan goPkg, 100)
 	go func() {
-		defer close(files)
+		defer close(pkgs)
 
 		const goext = ".go"
-		err := filepat.WalkDir(dir, func(file string, dirEntry fs.DirEntry, err error) error {
+
+		err := filepat.WalkDir(dir, func(path string, dirEntry fs.DirEntry, err error) error {
 			if err != nil {
 				return err
 			}
-			if filepat.Ext(dirEntry.Name()) == goext {
-				files <- file
+			if !dirEntry.IsDir() {
+				return nil
+			}
+			// Vendored dependencies aren't contributions
+			if dirEntry.Name() == "vendor" {
+				return filepat.SkipDir
+			}
+			if _, ok := dirs[path]; dirs != nil && !ok {
+				return nil
+			}
+
+			dirEntries, err := os.ReadDir(path)
+			if err != nil {
+				return err
+			}
+			var (
+				fset = token.NewFileSet()
+
+				names         = make([]string, 0)
+				files         = make(map[string][]string)
+				filePlatforms = make(map[string][]platform)
+			)
+			for _, dirEntry := range dirEntries {
+				if dirEntry.IsDir() || filepat.Ext(dirEntry.Name()) != goext {
+					continue
+				}
+				file := filepat.Join(path, dirEntry.Name())
+				if include != nil {
+					info, err := dirEntry.Info()
+					if err != nil || !include(file, info.Size()) {
+						continue
+					}
+				}
+
+				srcFile, err := parser.ParseFile(fset, file, nil, parser.ImportsOnly|parser.ParseComments)
+				if err != nil {
+					pkgs <- newSingleGoPkg(file)
+					continue
+				}
+				platforms := matchPlatforms(file, srcFile)
+				if len(platforms) == 0 {
+					// Files gated by custom build tags are type-checked on
+					// their own, since files of the opposite constraint may
+					// declare the same identifiers
+					pkg := newSingleGoPkg(file)
+					if custom := matchCustomPlatforms(file, srcFile); len(custom) > 0 {
+						pkg.platform = custom[0]
+						for _, platform := range custom {
+							pkg.platforms[file] = append(pkg.platforms[file], platform.String())
+						}
+					}
+					pkgs <- pkg
+					continue
+				}
+				filePlatforms[file] = platforms
+
+				name := srcFile.Name.Name
+				if _, ok := files[name]; !ok {
+					names = append(names, name)
+				}
+				files[name] = append(files[name], file)
+			}
+
+			for _, name := range names {
+				checked := make(map[string]struct{})
+				for _, platform := range platforms {
+					pkg := goPkg{
+						platform:  platform,
+						files:     make([]string, 0),
+						platforms: make(map[string][]string),
+					}
+					for _, file := range files[name] {
+						if !slices.Contains(filePlatforms[file], platform) {
+							continue
+						}
+						pkg.files = append(pkg.files, file)
+
+						if _, ok := checked[file]; ok {
+							continue
+						}
+						checked[file] = struct{}{}
+						for _, platform := range filePlatforms[file] {
+							pkg.platforms[file] = append(pkg.platforms[file], platform.String())
+						}
+					}
+					if len(pkg.platforms) > 0 {
+						pkgs <- pkg
+					}
+				}
 			}
 			return nil
 		})
 		checkErr(err)
 	}()
-	return files
+	return pkgs
+}
+
+// Package of a file not built for any platform, type-checked for the host
+// platform
+func newSingleGoPkg(file string) goPkg {
+	return goPkg{
+		platform:  hostPlatform,
+		files:     []string{file},
+		platforms: map[string][]string{file: nil},
+	}
+}
+
+// Directory relative to the repository as stored with contributions, e. g.
+// "/cmd/gh" or "/"
+func contribDir(repoDir, dir string) string {
+	return filepat.Join("/", dir[len(repoDir):])
 }
 
-// Removes directories like "vendor"
+// Removes directories like ".git". Vendored dependencies are kept to resolve
+// imports offline.
 func rmExtraneous(logger *log.Logger, dir string) {
 	logErr(logger, os.RemoveAll(fmt.Sprintf("%s/.git", dir)))
-
-	_ = filepat.WalkDir(dir, func(path string, dirEntry fs.DirEntry, err error) error {
-		if dirEntry.IsDir() {
-			if dir := filepat.Base(path); dir == "vendor" {
-				logErr(logger, os.RemoveAll(path))
-			}
-		}
-		return nil
-	})
 }
 
 func saveCatalogue(ctx context.Context, contribsn, reposn int) error {
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Manifest of curated repositories, used unless another manifest is passed
+//
+//go:embed repos.json
+var defaultManifest []byte
+
+// Version of the manifest format
+const manifestVersion = 1
+
+// Curated repositories to extract, e. g. "repos.json"
+type manifest struct {
+	Version int            `json:"version"`
+	Policy  licensePolicy  `json:"policy,omitzero"`
+	Repos   []manifestRepo `json:"repos"`
+}
+
+// Policies of publishing contributions by license, e. g. "snippet" for
+// "AGPL-3.0"
+type licensePolicy struct {
+	// Policy of unknown and unlisted licenses, defaults to "allow"
+	Default string `json:"default,omitempty"`
+	// Policies by SPDX identifier, e. g. "AGPL-3.0"
+	Licenses map[string]string `json:"licenses,omitempty"`
+}
+
+type manifestRepo struct {
+	Owner   string `json:"owner"`   // cli
+	Name    string `json:"name"`    // cli
+	Author  string `json:"author"`  // GitHub Inc.
+	License string `json:"license"` // MIT license
+	// Globs of paths relative to the repository. Globs without a slash
+	// match names of files and directories, e. g. "testdata" or
+	// "*_windows.go". Other globs match paths and their parent
+	// directories, e. g. "internal/*" or "cmd/gh/main.go". Paths are
+	// included if they match any include glob, all if there is none, and no
+	// exclude glob.
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	// Branch or tag to extract, e. g. "v2.40.0". Defaults to the default
+	// branch.
+	Ref    string `json:"ref,omitempty"`
+	Limits limits `json:"limits,omitzero"`
+	// Overrides the policy of the license, e. g. "deny"
+	Policy string `json:"policy,omitempty"`
+}
+
+// Hashes the entry, e. g. to re-extract the repository after changes of globs
+// or limits
+func (repo manifestRepo) hash() string {
+	data, _ := json.Marshal(repo)
+	return hashCode(data)
+}
+
+// Limits of a repository, no limit if zero
+type limits struct {
+	MaxFiles    int   `json:"max_files,omitempty"`     // Go files to extract
+	MaxFileSize int64 `json:"max_file_size,omitempty"` // Bytes of Go files to extract
+}
+
+var (
+	// GitHub owners and repository names, e. g. "go-critic" or "cosmos-sdk"
+	validRepoName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	// Git references, e. g. "release-1.2" or "v2.40.0"
+	validRef = regexp.MustCompile(`^[A-Za-z0-9_./-]+$`)
+)
+
+// Loads the manifest from a file, the default manifest if the name is empty
+func loadManifestFile(name string) (manifest, error) {
+	if name == "" {
+		return loadManifest(defaultManifest)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return manifest{}, err
+	}
+	man, err := loadManifest(data)
+	if err != nil {
+		return manifest{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return man, nil
+}
+
+// Decodes and validates a manifest. Unknown fields are errors, e. g. typos
+// like "exlude".
+func loadManifest(data []byte) (manifest, error) {
+	var man manifest
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&man); err != nil {
+		return manifest{}, fmt.Errorf("can't decode manifest: %w", err)
+	}
+	if err := man.validate(); err != nil {
+		return manifest{}, err
+	}
+	return man, nil
+}
+
+// Reports all errors, e. g. "repos[3] (cli/cli): missing license"
+func (man manifest) validate() error {
+	if man.Version != manifestVersion {
+		return fmt.Errorf("unsupported manifest version %d, want: %d", man.Version, manifestVersion)
+	}
+	if len(man.Repos) == 0 {
+		return errors.New("no repos in manifest")
+	}
+
+	var (
+		errs = make([]error, 0)
+		seen = make(map[string]int)
+	)
+	if man.Policy.Default != "" && !slices.Contains(policies, man.Policy.Default) {
+		errs = append(errs, fmt.Errorf("policy: invalid default %q", man.Policy.Default))
+	}
+	for _, id := range slices.Sorted(maps.Keys(man.Policy.Licenses)) {
+		if !slices.ContainsFunc(licenseRules, func(rule licenseRule) bool { return rule.spdx == id }) {
+			errs = append(errs, fmt.Errorf("policy: unknown SPDX identifier %q", id))
+		}
+		if policy := man.Policy.Licenses[id]; !slices.Contains(policies, policy) {
+			errs = append(errs, fmt.Errorf("policy: invalid policy %q of %s", policy, id))
+		}
+	}
+	for i, repo := range man.Repos {
+		id := repo.Owner + "/" + repo.Name
+		fail := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("repos[%d] (%s): %s", i, id, fmt.Sprintf(format, args...)))
+		}
+
+		if !validRepoName.MatchString(repo.Owner) {
+			fail("invalid owner %q", repo.Owner)
+		}
+		if !validRepoName.MatchString(repo.Name) {
+			fail("invalid name %q", repo.Name)
+		}
+		// GitHub names are case-insensitive
+		if j, ok := seen[strings.ToLower(id)]; ok {
+			fail("duplicate of repos[%d]", j)
+		} else {
+			seen[strings.ToLower(id)] = i
+		}
+		if strings.TrimSpace(repo.Author) == "" {
+			fail("missing author")
+		}
+		if strings.TrimSpace(repo.License) == "" {
+			fail("missing license")
+		}
+		for _, glob := range slices.Concat(repo.Include, repo.Exclude) {
+			if _, err := path.Match(glob, ""); glob == "" || err != nil {
+				fail("invalid glob %q", glob)
+			}
+		}
+		if repo.Ref != "" && (!validRef.MatchString(repo.Ref) || strings.HasPrefix(repo.Ref, "-")) {
+			fail("invalid ref %q", repo.Ref)
+		}
+		if repo.Limits.MaxFiles < 0 {
+			fail("negative max_files %d", repo.Limits.MaxFiles)
+		}
+		if repo.Limits.MaxFileSize < 0 {
+			fail("negative max_file_size %d", repo.Limits.MaxFileSize)
+		}
+		if repo.Policy != "" && !slices.Contains(policies, repo.Policy) {
+			fail("invalid policy %q", repo.Policy)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Reports whether a path relative to the repository, e. g. "cmd/gh/main.go",
+// is included by globs. Globs are valid.
+func includePath(rel string, include, exclude []string) bool {
+	if len(include) > 0 && !slices.ContainsFunc(include, func(glob string) bool { return matchGlob(glob, rel) }) {
+		return false
+	}
+	return !slices.ContainsFunc(exclude, func(glob string) bool { return matchGlob(glob, rel) })
+}
+
+// Matches a glob against the path and its parent directories, e. g.
+// "internal/*" matches "internal/config/config.go". Globs without a slash
+// match names, e. g. "testdata" matches "pkg/testdata/x.go".
+func matchGlob(glob, rel string) bool {
+	for p := rel; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		name := p
+		if !strings.Contains(glob, "/") {
+			name = path.Base(p)
+		}
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadManifest_default(t *testing.T) {
+	man, err := loadManifestFile("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(man.Repos) == 0 {
+		t.Fatal("want repos")
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		// Substrings of the error, none if empty
+		errs []string
+	}{
+		{
+			name: "valid",
+			data: `{"version": 1, "repos": [{
+				"owner": "cli", "name": "cli", "author": "GitHub Inc.", "license": "MIT license",
+				"include": ["cmd/*", "pkg"], "exclude": ["*_windows.go"], "ref": "v2.40.0",
+				"limits": {"max_files": 100, "max_file_size": 65536}, "policy": "allow"
+			}], "policy": {"default": "attribute", "licenses": {"AGPL-3.0": "snippet"}}}`,
+		},
+		{
+			name: "version",
+			data: `{"version": 2, "repos": []}`,
+			errs: []string{"unsupported manifest version 2"},
+		},
+		{
+			name: "unknown field",
+			data: `{"version": 1, "repos": [{"owner": "cli", "name": "cli", "exlude": ["docs"]}]}`,
+			errs: []string{`unknown field "exlude"`},
+		},
+		{
+			name: "policy",
+			data: `{"version": 1, "policy": {"default": "maybe", "licenses": {"WTFPL": "allow", "MIT": "never"}},
+				"repos": [{"owner": "cli", "name": "cli", "author": "GitHub Inc.", "license": "MIT license", "policy": "hide"}]}`,
+			errs: []string{
+				`policy: invalid default "maybe"`,
+				`policy: unknown SPDX identifier "WTFPL"`,
+				`policy: invalid policy "never" of MIT`,
+				`repos[0] (cli/cli): invalid policy "hide"`,
+			},
+		},
+		{
+			name: "no repos",
+			data: `{"version": 1}`,
+			errs: []string{"no repos"},
+		},
+		{
+			name: "invalid repos",
+			data: `{"version": 1, "repos": [
+				{"owner": "cli", "name": "cli", "author": "GitHub Inc."},
+				{"owner": "CLI", "name": "cli", "author": "GitHub Inc.", "license": "MIT license"},
+				{"owner": "a b", "name": "", "author": "", "license": "MIT license"},
+				{"owner": "cli", "name": "go-gh", "author": "GitHub Inc.", "license": "MIT license",
+				 "exclude": ["[a-"], "ref": "--upload-pack=x", "limits": {"max_files": -1}}
+			]}`,
+			errs: []string{
+				"repos[0] (cli/cli): missing license",
+				"repos[1] (CLI/cli): duplicate of repos[0]",
+				`repos[2] (a b/): invalid owner "a b"`,
+				`repos[2] (a b/): invalid name ""`,
+				"repos[2] (a b/): missing author",
+				`repos[3] (cli/go-gh): invalid glob "[a-"`,
+				`repos[3] (cli/go-gh): invalid ref "--upload-pack=x"`,
+				"repos[3] (cli/go-gh): negative max_files -1",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := loadManifest([]byte(test.data))
+			if len(test.errs) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("want errors: %v", test.errs)
+			}
+			for _, want := range test.errs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("loadManifest() = %q, want: %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIncludePath(t *testing.T) {
+	tests := []struct {
+		rel              string
+		include, exclude []string
+		want             bool
+	}{
+		{"cmd/gh/main.go", nil, nil, true},
+		{"cmd/gh/main.go", []string{"cmd/*"}, nil, true},
+		{"cmd/gh/main.go", []string{"cmd/gh/main.go"}, nil, true},
+		{"pkg/cmd/gh/main.go", []string{"cmd/*"}, nil, false},
+		{"pkg/testdata/x.go", nil, []string{"testdata"}, false},
+		{"pkg/x_windows.go", nil, []string{"*_windows.go"}, false},
+		{"pkg/x_linux.go", nil, []string{"*_windows.go"}, true},
+		{"internal/docs/gen.go", []string{"internal/*"}, []string{"docs"}, false},
+	}
+	for _, test := range tests {
+		if got := includePath(test.rel, test.include, test.exclude); got != test.want {
+			t.Errorf("includePath(%s, %v, %v) = %t, want: %t", test.rel, test.include, test.exclude, got, test.want)
+		}
+	}
+}
+
+func TestRepository_includeFile(t *testing.T) {
+	repo := repository{
+		exclude: []string{"vendor"},
+		limits:  limits{MaxFileSize: 100},
+	}
+	for _, test := range []struct {
+		file string
+		size int64
+		want bool
+	}{
+		{"/tmp/cli/main.go", 100, true},
+		{"/tmp/cli/main.go", 101, false},
+		{"/tmp/cli/vendor/x/x.go", 10, false},
+	} {
+		if got := repo.includeFile("/tmp/cli", test.file, test.size); got != test.want {
+			t.Errorf("includeFile(%s, %d) = %t, want: %t", test.file, test.size, got, test.want)
+		}
+	}
+}
+
+func TestManifestRepo_hash(t *testing.T) {
+	repo := manifestRepo{Owner: "cli", Name: "cli", Author: "GitHub Inc.", License: "MIT license"}
+	changed := repo
+	changed.Exclude = []string{"testdata"}
+	if repo.hash() != repo.hash() {
+		t.Fatal("manifestRepo.hash() isn't deterministic")
+	}
+	if repo.hash() == changed.hash() {
+		t.Fatal("manifestRepo.hash() doesn't change with globs")
+	}
+}
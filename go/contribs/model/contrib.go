@@ -2,16 +2,86 @@ package model
 
 type (
 	Contrib struct {
-		Locus     []Locus `json:"locus" bson:"locus"`
-		Code      string  `json:"code" bson:"code"`
-		Filename  string  `json:"filename" bson:"filename"`
-		Filepath  string  `json:"filepath" bson:"filepath"`
-		RepoName  string  `json:"repo_name" bson:"repo_name"`
-		RepoOwner string  `json:"repo_owner" bson:"repo_owner"`
+		Locus          []Locus  `json:"locus" bson:"locus"`
+		Category       string   `json:"category" bson:"category"`                           // production, test
+		Commit         string   `json:"commit" bson:"commit"`                               // SHA of the extracted commit
+		Diagnostics    []string `json:"diagnostics,omitempty" bson:"diagnostics,omitempty"` // 7:2: could not import github.com/google/uuid
+		Filename       string   `json:"filename" bson:"filename"`
+		Filepath       string   `json:"filepath" bson:"filepath"`
+		FileCopyrights []string `json:"file_copyrights,omitempty" bson:"file_copyrights,omitempty"` // Of leading comments, The Go Authors.
+		FileLicense    string   `json:"file_license,omitempty" bson:"file_license,omitempty"`       // Of leading comments, BSD-3-Clause
+		Hash           string   `json:"hash" bson:"hash"`                                           // SHA-256 of code, blob ID
+		IsGenerated    bool     `json:"is_generated" bson:"is_generated"`                           // Code generated by protoc-gen-go. DO NOT EDIT.
+		License        string   `json:"license,omitempty" bson:"license,omitempty"`                 // SPDX expression of the repository, MIT
+		Platforms      []string `json:"platforms,omitempty" bson:"platforms,omitempty"`             // linux/amd64, windows/arm64
+		Policy         string   `json:"policy,omitempty" bson:"policy,omitempty"`                   // allow, attribute, snippet
+		RepoName       string   `json:"repo_name" bson:"repo_name"`
+		RepoOwner      string   `json:"repo_owner" bson:"repo_owner"`
+		RepoURL        string   `json:"repo_url" bson:"repo_url"`     // https://github.com/cli/cli, empty for local repositories
+		TokenHash      string   `json:"token_hash" bson:"token_hash"` // SHA-256 of tokens, ignoring comments and formatting
+	}
+
+	// Code of contributions, stored once for identical files
+	Blob struct {
+		ID   string `json:"_id" bson:"_id"` // SHA-256 of code
+		Code string `json:"code" bson:"code"`
 	}
 
 	Locus struct {
-		Ident string `json:"ident" bson:"ident"` // bytes.Buffer, time.Now
-		Line  int    `json:"line" bson:"line"`   // 4
+		Ident    string  `json:"ident" bson:"ident"`                             // bytes.Buffer, time.Now
+		Kind     string  `json:"kind,omitempty" bson:"kind,omitempty"`           // call, type
+		TypeArgs string  `json:"type_args,omitempty" bson:"type_args,omitempty"` // []int, time.Duration
+		Line     int     `json:"line" bson:"line"`                               // 4
+		Span     Span    `json:"span" bson:"span"`
+		Snippet  Span    `json:"snippet" bson:"snippet"`
+		Score    float64 `json:"score" bson:"score"` // 0.82, between 0 and 1
+	}
+
+	// Span of the enclosing expression, e. g. the call "time.Now()", or
+	// snippet of the enclosing top-level declaration including its doc
+	// comment, e. g. "func main() { ... }"
+	Span struct {
+		Start Pos `json:"start" bson:"start"`
+		End   Pos `json:"end" bson:"end"`
+	}
+
+	Pos struct {
+		Line   int `json:"line" bson:"line"`     // 4
+		Column int `json:"column" bson:"column"` // 12, in bytes
+		Offset int `json:"offset" bson:"offset"` // 96, in bytes
 	}
 )
+
+// Locus kinds
+const (
+	// Function or method call, e. g. "time.Now()"
+	KindCall = "call"
+	// Composite literal, e. g. "http.Client{}"
+	KindComposite = "composite"
+	// Conversion, e. g. "time.Duration(n)"
+	KindConversion = "conversion"
+	// Embedded field, e. g. "struct{ sync.Mutex }"
+	KindEmbedded = "embedded"
+	// Side effect import, e. g. "import _ \"embed\""
+	KindImport = "import"
+	// Method value or expression, e. g. "buf.Write" or "(*bytes.Buffer).Write"
+	KindMethodValue = "method_value"
+	// Type expression, e. g. "var t time.Time"
+	KindType = "type"
+	// Constant, variable, field or function value, e. g. "os.Args"
+	KindValue = "value"
+)
+
+// Contribution categories
+const (
+	// Non-test file, e. g. "client.go"
+	CategoryProduction = "production"
+	// Test file, e. g. "func TestClient(t *testing.T)"
+	CategoryTest = "test"
+	// Test file of benchmarks, e. g. "func BenchmarkClient(b *testing.B)"
+	CategoryBenchmark = "benchmark"
+	// Test file of fuzz tests, e. g. "func FuzzClient(f *testing.F)"
+	CategoryFuzz = "fuzz"
+	// Test file of examples, e. g. "func ExampleClient()"
+	CategoryExample = "example"
+)
@@ -0,0 +1,11 @@
+package model
+
+// Indexed state of a repository
+type Repo struct {
+	ID         string   `json:"_id" bson:"_id"`                                   // cli/cli
+	Commit     string   `json:"commit" bson:"commit"`                             // SHA of the indexed HEAD commit
+	SPDX       string   `json:"spdx,omitempty" bson:"spdx,omitempty"`             // Detected license, e. g. MIT or Apache-2.0 AND MIT
+	Copyrights []string `json:"copyrights,omitempty" bson:"copyrights,omitempty"` // GitHub Inc.
+	Policy     string   `json:"policy,omitempty" bson:"policy,omitempty"`         // allow, attribute, snippet, deny
+	Config     string   `json:"config,omitempty" bson:"config,omitempty"`         // SHA-256 of the manifest entry
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path"
+	filepat "path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/go/packages"
+)
+
+// Go module of a cloned repository, e. g. the directory containing "go.mod".
+// Its dependency graph is loaded once per platform from the vendor
+// directory or the local module cache, never from the network. Loading
+// compiles the packages, including tests, and their dependencies, so at most
+// maxGraphPlatforms graphs are loaded. Not safe for concurrent use.
+type module struct {
+	dir string
+	// Module path, e. g. "github.com/cli/cli/v2". Empty if "go.mod" is
+	// invalid.
+	path string
+	// Directories of packages to load with their dependencies, e. g. changed
+	// directories of incremental runs. All if nil.
+	dirs map[string]struct{}
+
+	pkgs map[platform]map[string]*types.Package
+	// Imports of external test packages by import path of the tested
+	// package. Imports of tested packages are test variants, e. g. including
+	// declarations of "export_test.go".
+	xtests map[platform]map[string]map[string]*types.Package
+	errs   map[platform]error
+}
+
+func newModule(dir string) *module {
+	return &module{
+		dir:    dir,
+		pkgs:   make(map[platform]map[string]*types.Package),
+		xtests: make(map[platform]map[string]map[string]*types.Package),
+		errs:   make(map[platform]error),
+	}
+}
+
+// Finds modules of a repository, including nested modules. Vendored modules
+// aren't modules of the repository.
+func findModules(dir string) []*module {
+	mods := make([]*module, 0)
+	_ = filepat.WalkDir(dir, func(path string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if dirEntry.IsDir() {
+			switch dirEntry.Name() {
+			case "vendor", "testdata":
+				return filepat.SkipDir
+			}
+			return nil
+		}
+		if dirEntry.Name() == "go.mod" {
+			mod := newModule(filepat.Dir(path))
+			if data, err := os.ReadFile(path); err == nil {
+				mod.path = modfile.ModulePath(data)
+			}
+			mods = append(mods, mod)
+		}
+		return nil
+	})
+	return mods
+}
+
+// Finds the innermost module containing the directory. Returns nil if there
+// is none.
+func findModule(mods []*module, dir string) *module {
+	var found *module
+	for _, mod := range mods {
+		rel, err := filepat.Rel(mod.dir, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepat.Separator)) {
+			continue
+		}
+		if found == nil || len(mod.dir) > len(found.dir) {
+			found = mod
+		}
+	}
+	return found
+}
+
+// Finds directories of packages importing packages of the directories, e. g.
+// "/repo/cmd/gh" importing "/repo/pkg/cmd" via "github.com/cli/cli/v2/pkg/cmd",
+// directly or indirectly. The directories are contained.
+func findImporters(repoDir string, mods []*module, dirs map[string]struct{}) map[string]struct{} {
+	importPath := func(dir string) string {
+		mod := findModule(mods, dir)
+		if mod == nil {
+			return ""
+		}
+		return mod.importPath(dir)
+	}
+
+	// Directories by imported path
+	importers := make(map[string][]string)
+	fset := token.NewFileSet()
+	_ = filepat.WalkDir(repoDir, func(file string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if dirEntry.IsDir() {
+			if dirEntry.Name() == "vendor" {
+				return filepat.SkipDir
+			}
+			return nil
+		}
+		if filepat.Ext(file) != ".go" {
+			return nil
+		}
+		srcFile, err := parser.ParseFile(fset, file, nil, parser.ImportsOnly)
+		if err != nil {
+			return nil
+		}
+		for _, spec := range srcFile.Imports {
+			if imp, err := strconv.Unquote(spec.Path.Value); err == nil {
+				importers[imp] = append(importers[imp], filepat.Dir(file))
+			}
+		}
+		return nil
+	})
+
+	found := make(map[string]struct{}, len(dirs))
+	queue := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		found[dir] = struct{}{}
+		queue = append(queue, dir)
+	}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+		imp := importPath(dir)
+		if imp == "" {
+			continue
+		}
+		for _, importer := range importers[imp] {
+			if _, ok := found[importer]; !ok {
+				found[importer] = struct{}{}
+				queue = append(queue, importer)
+			}
+		}
+	}
+	return found
+}
+
+// Finds the import path of a directory of the module, e. g.
+// "github.com/cli/cli/v2/pkg/cmd" for "/repo/pkg/cmd". Returns an empty
+// string if unknown.
+func (mod *module) importPath(dir string) string {
+	if mod.path == "" {
+		return ""
+	}
+	rel, err := filepat.Rel(mod.dir, dir)
+	if err != nil {
+		return ""
+	}
+	return path.Join(mod.path, filepat.ToSlash(rel))
+}
+
+// Graphs loaded per module. Packages of other platforms, e. g. of
+// "_windows.go" files, are rare and type-checked from source instead.
+const maxGraphPlatforms = 3
+
+// Finds the package by import path, including standard library packages, in
+// the dependency graph of the platform. Imports of external test packages,
+// e. g. "net/url_test", are those of the tested package, e. g. "net/url", if
+// any. Reports false if the package isn't part of the graph or the graph
+// can't be loaded offline.
+func (mod *module) lookup(platform platform, forTest, path string) (*types.Package, bool) {
+	if _, ok := mod.pkgs[platform]; !ok {
+		if _, ok := mod.errs[platform]; ok {
+			return nil, false
+		}
+		if len(mod.pkgs) >= maxGraphPlatforms {
+			mod.errs[platform] = fmt.Errorf("graphs of more than %d platforms aren't loaded", maxGraphPlatforms)
+			return nil, false
+		}
+
+		pkgs, xtests, err := mod.load(platform)
+		if err != nil {
+			mod.errs[platform] = err
+			return nil, false
+		}
+		mod.pkgs[platform] = pkgs
+		mod.xtests[platform] = xtests
+	}
+
+	if pkg, ok := mod.xtests[platform][forTest][path]; ok {
+		return pkg, true
+	}
+	pkg, ok := mod.pkgs[platform][path]
+	return pkg, ok
+}
+
+// Finds patterns of packages to load, e. g. "./cmd/gh" of changed directories
+// or "./..." of all
+func (mod *module) patterns() []string {
+	if mod.dirs == nil {
+		return []string{"./..."}
+	}
+	patterns := make([]string, 0, len(mod.dirs))
+	for dir := range mod.dirs {
+		// Deleted directories have no packages
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		rel, err := filepat.Rel(mod.dir, dir)
+		if err != nil {
+			continue
+		}
+		patterns = append(patterns, "./"+filepat.ToSlash(rel))
+	}
+	slices.Sort(patterns)
+	return patterns
+}
+
+// Reports whether the dependency graph of the platform has been loaded
+func (mod *module) loaded(platform platform) bool {
+	_, ok := mod.pkgs[platform]
+	return ok
+}
+
+// Loads the dependency graph from export data. Vendored dependencies take
+// precedence over the module cache.
+func (mod *module) load(platform platform) (map[string]*types.Package, map[string]map[string]*types.Package, error) {
+	flags := "-mod=mod -buildvcs=false"
+	if _, err := os.Stat(filepat.Join(mod.dir, "vendor", "modules.txt")); err == nil {
+		flags = "-mod=vendor -buildvcs=false"
+	}
+
+	roots, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedImports | packages.NeedDeps,
+		Dir:  mod.dir,
+		Env: append(os.Environ(),
+			"GOFLAGS="+flags,
+			"GOPROXY=off",
+			"GOWORK=off",
+			"GOOS="+platform.goos,
+			"GOARCH="+platform.goarch,
+			"CGO_ENABLED=0",
+		),
+		Tests: true,
+	}, mod.patterns()...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(roots) == 0 {
+		return nil, nil, fmt.Errorf("no packages in module %s", mod.dir)
+	}
+
+	var (
+		pkgs   = make(map[string]*types.Package)
+		xtests = make(map[string]map[string]*types.Package)
+	)
+	packages.Visit(roots, nil, func(pkg *packages.Package) {
+		// External test packages, e. g. "net/url_test [net/url.test]",
+		// import test variants
+		if forTest, ok := strings.CutSuffix(pkg.PkgPath, "_test"); ok && pkg.ID != pkg.PkgPath {
+			imports := make(map[string]*types.Package, len(pkg.Imports))
+			for path, imp := range pkg.Imports {
+				if imp.Types != nil && imp.Types.Complete() {
+					imports[path] = imp.Types
+				}
+			}
+			xtests[forTest] = imports
+			return
+		}
+		// Test variants, e. g. "net/url [net/url.test]", and packages
+		// without export data, e. g. because of compile errors
+		if pkg.ID != pkg.PkgPath || pkg.Types == nil || !pkg.Types.Complete() {
+			return
+		}
+		pkgs[pkg.PkgPath] = pkg.Types
+	})
+	return pkgs, xtests, nil
+}
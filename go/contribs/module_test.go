@@ -0,0 +1,270 @@
+package main
+
+import (
+	"os"
+	filepat "path/filepath"
+	"reflect"
+	"testing"
+
+	"contribs-go/model"
+)
+
+func TestModule_lookup(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"internal/greet/greet.go": `package greet
+
+import "net/http"
+
+func Client() *http.Client {
+	return http.DefaultClient
+}
+`,
+		"main.go": `package main
+
+import "example.com/app/internal/greet"
+
+func main() {
+	greet.Client().Get("https://example.com")
+}
+`,
+	} {
+		file := filepat.Join(dir, name)
+		if err := os.MkdirAll(filepat.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mods := findModules(dir)
+	mod := findModule(mods, filepat.Join(dir, "internal", "greet"))
+	if mod == nil || mod.dir != dir {
+		t.Fatalf("findModule() = %v, want: %s", mod, dir)
+	}
+
+	src, err := os.ReadFile(filepat.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := newPkgExtractor([]srcFile{{name: filepat.Join(dir, "main.go"), src: src}}, hostPlatform, mod)
+	if ex.Error != nil {
+		t.Fatal(ex.Error)
+	}
+	if err := mod.errs[hostPlatform]; err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[model.Locus]struct{}{
+		{
+			Ident: "net/http.Client.Get",
+			Kind:  model.KindCall,
+			Line:  6,
+		}: {},
+	}
+	if got := withoutSpans(ex.Extract()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Extractor.Extract()\ngot 	= %v\nwant 	= %v", got, want)
+	}
+	if diagnostics := ex.Diagnostics(); len(diagnostics) > 0 {
+		t.Errorf("Extractor.Diagnostics() = %v", diagnostics)
+	}
+}
+
+func TestFindImporters(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod":                        "module example.com/app\n\ngo 1.21\n",
+		"internal/greet/greet.go":       "package greet\n",
+		"internal/hello/hello.go":       "package hello\n\nimport _ \"example.com/app/internal/greet\"\n",
+		"main.go":                       "package main\n\nimport _ \"example.com/app/internal/hello\"\n",
+		"cmd/other/main.go":             "package main\n\nimport _ \"fmt\"\n",
+		"vendor/example.com/lib/lib.go": "package lib\n\nimport _ \"example.com/app/internal/greet\"\n",
+	} {
+		file := filepat.Join(dir, name)
+		if err := os.MkdirAll(filepat.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changed := map[string]struct{}{filepat.Join(dir, "internal", "greet"): {}}
+	got := findImporters(dir, findModules(dir), changed)
+	// Importers of importers are contained
+	want := map[string]struct{}{
+		filepat.Join(dir, "internal", "greet"): {},
+		filepat.Join(dir, "internal", "hello"): {},
+		dir:                                    {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("findImporters() = %v, want: %v", got, want)
+	}
+}
+
+// External test packages use declarations of "export_test.go"
+func TestModule_lookup_xtest(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"greet/greet.go": `package greet
+
+import "net/http"
+
+func client() *http.Client {
+	return http.DefaultClient
+}
+`,
+		"greet/export_test.go": `package greet
+
+var Client = client
+`,
+		"greet/greet_test.go": `package greet_test
+
+import (
+	"testing"
+
+	"example.com/app/greet"
+)
+
+func TestClient(t *testing.T) {
+	greet.Client().Get("https://example.com")
+}
+`,
+	} {
+		file := filepat.Join(dir, name)
+		if err := os.MkdirAll(filepat.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mod := findModule(findModules(dir), filepat.Join(dir, "greet"))
+	file := filepat.Join(dir, "greet", "greet_test.go")
+	src, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := newPkgExtractor([]srcFile{{name: file, src: src}}, hostPlatform, mod)
+	if ex.Error != nil {
+		t.Fatal(ex.Error)
+	}
+	if err := mod.errs[hostPlatform]; err != nil {
+		t.Fatal(err)
+	}
+
+	want := model.Locus{
+		Ident: "net/http.Client.Get",
+		Kind:  model.KindCall,
+		Line:  10,
+	}
+	if _, ok := withoutSpans(ex.Extract())[want]; !ok {
+		t.Errorf("Extractor.Extract() = %v, want: %v", ex.Extract(), want)
+	}
+	if diagnostics := ex.Diagnostics(); len(diagnostics) > 0 {
+		t.Errorf("Extractor.Diagnostics() = %v", diagnostics)
+	}
+}
+
+// Standard library packages missing from the graph, e. g. "net/http/httptest",
+// import packages of the graph, e. g. "net/http"
+func TestModule_lookup_std(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"greet/greet.go": `package greet
+
+import "net/http"
+
+func Serve(w http.ResponseWriter) {}
+`,
+	} {
+		file := filepat.Join(dir, name)
+		if err := os.MkdirAll(filepat.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Files excluded from the graph, e. g. by custom build tags
+	src := []byte(`//go:build integration
+
+package main
+
+import (
+	"net/http/httptest"
+
+	"example.com/app/greet"
+)
+
+func main() {
+	greet.Serve(httptest.NewRecorder())
+}
+`)
+	mod := findModule(findModules(dir), dir)
+	ex := newPkgExtractor([]srcFile{{name: filepat.Join(dir, "main.go"), src: src}}, hostPlatform, mod)
+	if ex.Error != nil {
+		t.Fatal(ex.Error)
+	}
+	if _, ok := mod.lookup(hostPlatform, "", "net/http/httptest"); ok {
+		t.Fatal("module.lookup() found net/http/httptest, want: missing from graph")
+	}
+
+	want := model.Locus{
+		Ident: "net/http/httptest.NewRecorder",
+		Kind:  model.KindCall,
+		Line:  12,
+	}
+	if _, ok := withoutSpans(ex.Extract())[want]; !ok {
+		t.Errorf("Extractor.Extract() = %v, want: %v", ex.Extract(), want)
+	}
+	if diagnostics := ex.Diagnostics(); len(diagnostics) > 0 {
+		t.Errorf("Extractor.Diagnostics() = %v", diagnostics)
+	}
+}
+
+// Graphs of incremental runs contain changed packages and their dependencies
+func TestModule_lookup_dirs(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod":                  "module example.com/app\n\ngo 1.21\n",
+		"internal/greet/greet.go": "package greet\n",
+		"internal/hello/hello.go": "package hello\n\nimport _ \"example.com/app/internal/greet\"\n",
+		"main.go":                 "package main\n",
+	} {
+		file := filepat.Join(dir, name)
+		if err := os.MkdirAll(filepat.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mod := findModule(findModules(dir), dir)
+	mod.dirs = map[string]struct{}{
+		filepat.Join(dir, "internal", "hello"):   {},
+		filepat.Join(dir, "internal", "deleted"): {},
+	}
+	if got, want := mod.patterns(), []string{"./internal/hello"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("module.patterns() = %v, want: %v", got, want)
+	}
+	for path, want := range map[string]bool{
+		"example.com/app/internal/hello": true,
+		"example.com/app/internal/greet": true,
+		"example.com/app":                false,
+	} {
+		if _, ok := mod.lookup(hostPlatform, "", path); ok != want {
+			t.Errorf("module.lookup(%q) = %t, want: %t", path, ok, want)
+		}
+	}
+	if err := mod.errs[hostPlatform]; err != nil {
+		t.Fatal(err)
+	}
+}
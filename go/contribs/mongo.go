@@ -1,10 +1,166 @@
 package main
 
-import "mongo"
+import (
+	"context"
+	"errors"
+	"mongo"
+
+	"contribs-go/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongodrv "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
 
 const (
 	catalogue_id = "_cat"
 	licenses_id  = "_licenses"
 )
 
-var mongoColl = mongo.Client.Database("contribs").Collection("go")
+var (
+	mongoColl = mongo.Client.Database("contribs").Collection("go")
+	// Code of contributions by hash
+	blobsColl = mongo.Client.Database("contribs").Collection("go_blobs")
+	// Indexed commits of repositories
+	reposColl = mongo.Client.Database("contribs").Collection("go_repos")
+)
+
+// Finds the indexed state of a repository. Returns a zero state if the
+// repository hasn't been indexed yet.
+func findRepoState(ctx context.Context, repoOwner, repoName string) (model.Repo, error) {
+	var repo model.Repo
+	err := reposColl.FindOne(ctx, bson.M{"_id": repoOwner + "/" + repoName}).Decode(&repo)
+	if errors.Is(err, mongodrv.ErrNoDocuments) {
+		return model.Repo{}, nil
+	}
+	return repo, err
+}
+
+func saveRepo(ctx context.Context, repo model.Repo) error {
+	_, err := reposColl.ReplaceOne(ctx,
+		bson.M{"_id": repo.ID},
+		repo,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// Finds indexed states of repositories by ID, e. g. "cli/cli"
+func findRepoStates(ctx context.Context, ids []string) (map[string]model.Repo, error) {
+	cur, err := reposColl.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	repos := make([]model.Repo, 0)
+	if err := cur.All(ctx, &repos); err != nil {
+		return nil, err
+	}
+	states := make(map[string]model.Repo, len(repos))
+	for _, repo := range repos {
+		states[repo.ID] = repo
+	}
+	return states, nil
+}
+
+// Counts contributions of all repositories, including repositories skipped
+// because they're unchanged
+func countContribs(ctx context.Context) (int, error) {
+	n, err := mongoColl.CountDocuments(ctx, bson.M{"locus": bson.M{"$exists": true}})
+	return int(n), err
+}
+
+// Counts repositories with contributions, including repositories of other
+// sources
+func countRepos(ctx context.Context) (int, error) {
+	cur, err := mongoColl.Aggregate(ctx, mongodrv.Pipeline{
+		{{Key: "$match", Value: bson.M{"locus": bson.M{"$exists": true}}}},
+		{{Key: "$group", Value: bson.M{"_id": bson.M{"owner": "$repo_owner", "name": "$repo_name"}}}},
+		{{Key: "$count", Value: "n"}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var counts []struct {
+		N int `bson:"n"`
+	}
+	if err := cur.All(ctx, &counts); err != nil {
+		return 0, err
+	}
+	if len(counts) == 0 {
+		return 0, nil
+	}
+	return counts[0].N, nil
+}
+
+// Inserts code by hash unless already stored, e. g. by another repository
+func insertBlobs(ctx context.Context, blobs map[string]string) error {
+	if len(blobs) == 0 {
+		return nil
+	}
+
+	models := make([]mongodrv.WriteModel, 0, len(blobs))
+	for hash, code := range blobs {
+		models = append(models, mongodrv.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": hash}).
+			SetUpdate(bson.M{"$setOnInsert": model.Blob{ID: hash, Code: code}}).
+			SetUpsert(true))
+	}
+	_, err := blobsColl.BulkWrite(ctx, models)
+	return err
+}
+
+// Deletes code no contribution refers to anymore. Blobs are joined with
+// contributions one by one, since hashes of all contributions exceed the size
+// of documents.
+func deleteOrphanBlobs(ctx context.Context) error {
+	// Joins look up contributions by hash
+	if _, err := mongoColl.Indexes().CreateOne(ctx, mongodrv.IndexModel{Keys: bson.D{{Key: "hash", Value: 1}}}); err != nil {
+		return err
+	}
+	cur, err := blobsColl.Aggregate(ctx, mongodrv.Pipeline{
+		{{Key: "$project", Value: bson.M{"_id": 1}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": mongoColl.Name(),
+			"let":  bson.M{"hash": "$_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$hash", "$$hash"}}}},
+				bson.M{"$limit": 1},
+				bson.M{"$project": bson.M{"_id": 1}},
+			},
+			"as": "contribs",
+		}}},
+		{{Key: "$match", Value: bson.M{"contribs": bson.M{"$size": 0}}}},
+		{{Key: "$project", Value: bson.M{"_id": 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	const batchSize = 1000
+	orphans := make([]string, 0, batchSize)
+	deleteOrphans := func() error {
+		if len(orphans) == 0 {
+			return nil
+		}
+		_, err := blobsColl.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": orphans}})
+		orphans = orphans[:0]
+		return err
+	}
+	for cur.Next(ctx) {
+		var blob model.Blob
+		if err := cur.Decode(&blob); err != nil {
+			return err
+		}
+		orphans = append(orphans, blob.ID)
+		if len(orphans) == batchSize {
+			if err := deleteOrphans(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	return deleteOrphans()
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	filepat "path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Platform a file is built for, e. g. "linux/amd64"
+type platform struct {
+	goos, goarch string
+}
+
+// Platforms files are matched against, see "go tool dist list". Files are
+// type-checked for the first matching platform.
+var platforms = []platform{
+	{"linux", "amd64"},
+	{"linux", "arm64"},
+	{"linux", "386"},
+	{"linux", "arm"},
+	{"darwin", "amd64"},
+	{"darwin", "arm64"},
+	{"windows", "amd64"},
+	{"windows", "arm64"},
+	{"windows", "386"},
+	{"freebsd", "amd64"},
+	{"openbsd", "amd64"},
+	{"netbsd", "amd64"},
+	{"js", "wasm"},
+	{"wasip1", "wasm"},
+}
+
+// Platform of the build context, for files that don't match any platform
+var hostPlatform = platform{build.Default.GOOS, build.Default.GOARCH}
+
+var (
+	knownOS = map[string]struct{}{
+		"aix": {}, "android": {}, "darwin": {}, "dragonfly": {}, "freebsd": {},
+		"hurd": {}, "illumos": {}, "ios": {}, "js": {}, "linux": {}, "nacl": {},
+		"netbsd": {}, "openbsd": {}, "plan9": {}, "solaris": {}, "wasip1": {},
+		"windows": {}, "zos": {},
+	}
+	knownArch = map[string]struct{}{
+		"386": {}, "amd64": {}, "amd64p32": {}, "arm": {}, "armbe": {},
+		"arm64": {}, "arm64be": {}, "loong64": {}, "mips": {}, "mipsle": {},
+		"mips64": {}, "mips64le": {}, "mips64p32": {}, "mips64p32le": {},
+		"ppc": {}, "ppc64": {}, "ppc64le": {}, "riscv": {}, "riscv64": {},
+		"s390": {}, "s390x": {}, "sparc": {}, "sparc64": {}, "wasm": {},
+	}
+	unixOS = map[string]struct{}{
+		"aix": {}, "android": {}, "darwin": {}, "dragonfly": {}, "freebsd": {},
+		"hurd": {}, "illumos": {}, "ios": {}, "linux": {}, "netbsd": {},
+		"openbsd": {}, "solaris": {},
+	}
+)
+
+func (p platform) String() string {
+	return fmt.Sprintf("%s/%s", p.goos, p.goarch)
+}
+
+// Build context of the platform. Cgo files can't be type-checked.
+func (p platform) context() build.Context {
+	ctxt := build.Default
+	ctxt.GOOS = p.goos
+	ctxt.GOARCH = p.goarch
+	ctxt.CgoEnabled = false
+	return ctxt
+}
+
+// Reports whether a build tag is satisfied, e. g. "linux", "unix" or
+// "go1.21". Custom tags and "cgo" aren't.
+func (p platform) matchTag(tag string) bool {
+	switch tag {
+	case p.goos, p.goarch, "gc":
+		return true
+
+	case "unix":
+		_, ok := unixOS[p.goos]
+		return ok
+	}
+	return slices.Contains(build.Default.ReleaseTags, tag)
+}
+
+// Reports whether a build tag is neither a platform, e. g. "linux" or "unix",
+// a toolchain, e. g. "go1.21" or "cgo", nor "ignore"
+func isCustomTag(tag string) bool {
+	_, isOS := knownOS[tag]
+	_, isArch := knownArch[tag]
+	switch {
+	case isOS, isArch, strings.HasPrefix(tag, "go1."):
+		return false
+	}
+	switch tag {
+	case "unix", "gc", "gccgo", "cgo", "ignore":
+		return false
+	}
+	return true
+}
+
+// Reports whether the file name suffixes are satisfied, e. g.
+// "file_windows_amd64.go" or "file_linux_test.go"
+func (p platform) matchFileName(name string) bool {
+	name, _, _ = strings.Cut(filepat.Base(name), ".")
+
+	// Everything before the first "_" is ignored, e. g. "linux.go"
+	i := strings.Index(name, "_")
+	if i < 0 {
+		return true
+	}
+	elems := strings.Split(name[i:], "_")
+	if n := len(elems); n > 0 && elems[n-1] == "test" {
+		elems = elems[:n-1]
+	}
+
+	n := len(elems)
+	if n >= 2 {
+		_, isOS := knownOS[elems[n-2]]
+		_, isArch := knownArch[elems[n-1]]
+		if isOS && isArch {
+			return p.matchTag(elems[n-2]) && p.matchTag(elems[n-1])
+		}
+	}
+	if n >= 1 {
+		_, isOS := knownOS[elems[n-1]]
+		_, isArch := knownArch[elems[n-1]]
+		if isOS || isArch {
+			return p.matchTag(elems[n-1])
+		}
+	}
+	return true
+}
+
+// Finds platforms a file is built for by its name and build constraints.
+// Files that are ignored, e. g. "_file.go", or use cgo aren't built for any
+// platform. The file must be parsed with comments.
+func matchPlatforms(name string, file *ast.File) []platform {
+	return matchPlatformsFunc(name, file, false)
+}
+
+// Finds platforms a file gated by custom build tags, e. g. "integration", is
+// built for if the custom tags are satisfied
+func matchCustomPlatforms(name string, file *ast.File) []platform {
+	return matchPlatformsFunc(name, file, true)
+}
+
+func matchPlatformsFunc(name string, file *ast.File, custom bool) []platform {
+	if base := filepat.Base(name); strings.HasPrefix(base, "_") || strings.HasPrefix(base, ".") {
+		return nil
+	}
+	for _, importSpec := range file.Imports {
+		if path, err := strconv.Unquote(importSpec.Path.Value); err == nil && path == "C" {
+			return nil
+		}
+	}
+
+	expr, ok := findConstraint(file)
+	if !ok {
+		return nil
+	}
+	matches := make([]platform, 0)
+	for _, p := range platforms {
+		if !p.matchFileName(name) {
+			continue
+		}
+		match := p.matchTag
+		if custom {
+			match = func(tag string) bool {
+				return p.matchTag(tag) || isCustomTag(tag)
+			}
+		}
+		if expr != nil && !expr.Eval(match) {
+			continue
+		}
+		matches = append(matches, p)
+	}
+	return matches
+}
+
+// Finds the build constraint above the package clause. "//go:build" lines
+// take precedence over "// +build" lines. Reports false for malformed
+// constraints.
+func findConstraint(file *ast.File) (constraint.Expr, bool) {
+	var plusBuild constraint.Expr
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+		for _, comment := range group.List {
+			switch {
+			case constraint.IsGoBuild(comment.Text):
+				expr, err := constraint.Parse(comment.Text)
+				return expr, err == nil
+
+			case constraint.IsPlusBuild(comment.Text):
+				expr, err := constraint.Parse(comment.Text)
+				if err != nil {
+					return nil, false
+				}
+				if plusBuild == nil {
+					plusBuild = expr
+					continue
+				}
+				plusBuild = &constraint.AndExpr{X: plusBuild, Y: expr}
+			}
+		}
+	}
+	return plusBuild, true
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"slices"
+	"testing"
+)
+
+func TestMatchPlatforms(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		src  string
+		want []platform
+	}{
+		{
+			name: "no constraints",
+			file: "main.go",
+			src:  "package main",
+			want: platforms,
+		},
+		{
+			name: "goos suffix",
+			file: "file_windows.go",
+			src:  "package main",
+			want: []platform{{"windows", "amd64"}, {"windows", "arm64"}, {"windows", "386"}},
+		},
+		{
+			name: "goos and goarch suffix",
+			file: "file_linux_arm64_test.go",
+			src:  "package main",
+			want: []platform{{"linux", "arm64"}},
+		},
+		{
+			name: "go:build",
+			file: "file.go",
+			src:  "//go:build darwin && !amd64\n\npackage main",
+			want: []platform{{"darwin", "arm64"}},
+		},
+		{
+			name: "go:build unix",
+			file: "file.go",
+			src:  "//go:build !unix\n\npackage main",
+			want: []platform{
+				{"windows", "amd64"},
+				{"windows", "arm64"},
+				{"windows", "386"},
+				{"js", "wasm"},
+				{"wasip1", "wasm"},
+			},
+		},
+		{
+			name: "+build",
+			file: "file.go",
+			src:  "// +build wasm\n\npackage main",
+			want: []platform{{"js", "wasm"}, {"wasip1", "wasm"}},
+		},
+		{
+			name: "suffix and go:build",
+			file: "file_linux.go",
+			src:  "//go:build 386 || arm\n\npackage main",
+			want: []platform{{"linux", "386"}, {"linux", "arm"}},
+		},
+		{
+			name: "custom tag",
+			file: "file.go",
+			src:  "//go:build integration\n\npackage main",
+			want: []platform{},
+		},
+		{
+			name: "cgo",
+			file: "file.go",
+			src:  "package main\n\nimport \"C\"",
+			want: nil,
+		},
+		{
+			name: "ignored",
+			file: "_file.go",
+			src:  "package main",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := parser.ParseFile(token.NewFileSet(), tt.file, tt.src, parser.ImportsOnly|parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := matchPlatforms(tt.file, file)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("matchPlatforms()\ngot 	= %v\nwant 	= %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchCustomPlatforms(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		src  string
+		want []platform
+	}{
+		{
+			name: "custom tag",
+			file: "file.go",
+			src:  "//go:build integration\n\npackage main",
+			want: platforms,
+		},
+		{
+			name: "custom tag and goos",
+			file: "file.go",
+			src:  "//go:build integration && darwin\n\npackage main",
+			want: []platform{{"darwin", "amd64"}, {"darwin", "arm64"}},
+		},
+		{
+			name: "negated custom tag",
+			file: "file.go",
+			src:  "//go:build !integration && windows\n\npackage main",
+			want: []platform{},
+		},
+		{
+			name: "ignore",
+			file: "file.go",
+			src:  "//go:build ignore\n\npackage main",
+			want: []platform{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := parser.ParseFile(token.NewFileSet(), tt.file, tt.src, parser.ImportsOnly|parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := matchCustomPlatforms(tt.file, file)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("matchCustomPlatforms()\ngot 	= %v\nwant 	= %v", got, tt.want)
+			}
+		})
+	}
+}
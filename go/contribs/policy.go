@@ -0,0 +1,110 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"contribs-go/model"
+)
+
+// Policies of publishing contributions, from most to least permissive
+const (
+	// Code is stored in full
+	policyAllow = "allow"
+	// Code is stored in full and served with attribution
+	policyAttribute = "attribute"
+	// Code is truncated to snippets of locus and served with attribution
+	policySnippet = "snippet"
+	// Repositories aren't extracted
+	policyDeny = "deny"
+)
+
+var policies = []string{policyAllow, policyAttribute, policySnippet, policyDeny}
+
+// Decides the policy of a repository by its SPDX expression, e. g. "snippet"
+// for "AGPL-3.0". Of licenses that all apply, e. g. "AGPL-3.0 AND MIT" of
+// several license files, the least permissive one counts. Of alternatives,
+// e. g. "Apache-2.0 OR MIT", the most permissive one counts. The repository's
+// policy takes precedence.
+func (p licensePolicy) decide(repoPolicy, spdx string) string {
+	if repoPolicy != "" {
+		return repoPolicy
+	}
+	def := cmp.Or(p.Default, policyAllow)
+	if spdx == "" {
+		return def
+	}
+	tokens := spdxTokens(spdx)
+	return p.decideOr(&tokens, def)
+}
+
+// Decides alternatives, e. g. "Apache-2.0 OR MIT". AND takes precedence over
+// OR.
+func (p licensePolicy) decideOr(tokens *[]string, def string) string {
+	decided := p.decideAnd(tokens, def)
+	for len(*tokens) > 0 && (*tokens)[0] == "OR" {
+		*tokens = (*tokens)[1:]
+		if policy := p.decideAnd(tokens, def); slices.Index(policies, policy) < slices.Index(policies, decided) {
+			decided = policy
+		}
+	}
+	return decided
+}
+
+// Decides licenses that all apply, e. g. "AGPL-3.0 AND MIT"
+func (p licensePolicy) decideAnd(tokens *[]string, def string) string {
+	decided := p.decideLicense(tokens, def)
+	for len(*tokens) > 0 && (*tokens)[0] == "AND" {
+		*tokens = (*tokens)[1:]
+		if policy := p.decideLicense(tokens, def); slices.Index(policies, policy) > slices.Index(policies, decided) {
+			decided = policy
+		}
+	}
+	return decided
+}
+
+// Decides a license, e. g. "GPL-2.0 WITH Classpath-exception-2.0", or a
+// parenthesized expression
+func (p licensePolicy) decideLicense(tokens *[]string, def string) string {
+	if len(*tokens) == 0 {
+		return def
+	}
+	token := (*tokens)[0]
+	*tokens = (*tokens)[1:]
+	if token == "(" {
+		decided := p.decideOr(tokens, def)
+		if len(*tokens) > 0 && (*tokens)[0] == ")" {
+			*tokens = (*tokens)[1:]
+		}
+		return decided
+	}
+	// Exceptions don't change policies
+	if len(*tokens) > 1 && (*tokens)[0] == "WITH" {
+		*tokens = (*tokens)[2:]
+	}
+	return cmp.Or(p.Licenses[token], def)
+}
+
+// Blanks lines of code outside of snippets of locus with spaces, e. g. all but
+// a function declaration. Lines of locus without snippet are kept. Line
+// numbers and byte offsets of spans don't change.
+func truncateToSnippets(src []byte, locus []model.Locus) []byte {
+	lines := strings.Split(string(src), "\n")
+	keep := make([]bool, len(lines))
+	for _, l := range locus {
+		start, end := l.Snippet.Start.Line, l.Snippet.End.Line
+		if start == 0 {
+			start, end = l.Line, l.Line
+		}
+		for line := max(start, 1); line <= min(end, len(lines)); line++ {
+			keep[line-1] = true
+		}
+	}
+	for i := range lines {
+		if !keep[i] {
+			lines[i] = strings.Repeat(" ", len(lines[i]))
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
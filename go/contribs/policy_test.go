@@ -0,0 +1,150 @@
+package main
+
+import (
+	"os"
+	filepat "path/filepath"
+	"strings"
+	"testing"
+
+	"contribs-go/model"
+)
+
+func TestLicensePolicy_decide(t *testing.T) {
+	p := licensePolicy{
+		Default: policyAttribute,
+		Licenses: map[string]string{
+			"AGPL-3.0": policySnippet,
+			"GPL-3.0":  policyDeny,
+			"MIT":      policyAllow,
+		},
+	}
+	tests := []struct {
+		name       string
+		policy     licensePolicy
+		repoPolicy string
+		spdx       string
+		want       string
+	}{
+		{"license", p, "", "AGPL-3.0", policySnippet},
+		{"default", p, "", "Apache-2.0", policyAttribute},
+		{"unknown", p, "", "", policyAttribute},
+		{"repo", p, policyDeny, "MIT", policyDeny},
+		{"most permissive", p, "", "GPL-3.0 OR MIT", policyAllow},
+		{"more permissive default", p, "", "GPL-3.0 OR Apache-2.0", policyAttribute},
+		{"license files", p, "", "AGPL-3.0 AND MIT", policySnippet},
+		{"less permissive default", p, "", "MIT AND Apache-2.0", policyAttribute},
+		{"precedence", p, "", "MIT OR AGPL-3.0 AND GPL-3.0", policyAllow},
+		{"parentheses", p, "", "(MIT OR GPL-3.0) AND AGPL-3.0", policySnippet},
+		{"exception", p, "", "GPL-3.0 WITH GCC-exception-3.1 OR MIT", policyAllow},
+		{"no policy", licensePolicy{}, "", "AGPL-3.0", policyAllow},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.policy.decide(test.repoPolicy, test.spdx); got != test.want {
+				t.Fatalf("decide() = %s, want: %s", got, test.want)
+			}
+		})
+	}
+}
+
+const agplText = `                    GNU AFFERO GENERAL PUBLIC LICENSE
+                       Version 3, 19 November 2007
+
+ Copyright (C) 2007 Free Software Foundation, Inc. <https://fsf.org/>
+`
+
+// AGPL projects often carry license files of permissive components
+func TestDecidePolicy_licenseFiles(t *testing.T) {
+	man, err := loadManifest(defaultManifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"AGPL", map[string]string{"LICENSE": agplText}, policySnippet},
+		{"AGPL and MIT", map[string]string{"LICENSE": agplText, "LICENSE-MIT": mitText}, policySnippet},
+		{"MIT", map[string]string{"LICENSE": mitText}, policyAttribute},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, text := range test.files {
+				if err := os.WriteFile(filepat.Join(dir, name), []byte(text), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			lic, err := detectRepoLicense(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			repo := repository{license: "MIT license", policies: man.Policy}
+			if got, _ := repo.decidePolicy(lic.spdx); got != test.want {
+				t.Fatalf("decidePolicy(%q) = %s, want: %s", lic.spdx, got, test.want)
+			}
+		})
+	}
+}
+
+func TestTruncateToSnippets(t *testing.T) {
+	src := `package main
+
+import "fmt"
+
+// Greets
+func main() {
+	fmt.Println("hello")
+}
+
+var s = fmt.Sprint(1)
+`
+	locus := []model.Locus{
+		{Ident: "fmt.Println", Line: 7, Snippet: model.Span{Start: model.Pos{Line: 5}, End: model.Pos{Line: 8}}},
+		{Ident: "fmt.Sprint", Line: 10},
+	}
+	// Line numbers and offsets don't change
+	want := "            \n\n            \n\n" + `// Greets
+func main() {
+	fmt.Println("hello")
+}
+
+var s = fmt.Sprint(1)
+`
+	if got := string(truncateToSnippets([]byte(src), locus)); got != want {
+		t.Fatalf("truncateToSnippets() = %q, want: %q", got, want)
+	}
+}
+
+// Spans of locus point into truncated code
+func TestTruncateToSnippets_offsets(t *testing.T) {
+	src := []byte(`package main
+
+import "fmt"
+
+// Greets
+func main() {
+	fmt.Println("héllo")
+}
+
+var s = fmt.Sprint(1)
+`)
+	locus := make([]model.Locus, 0)
+	for l := range newExtractor(src).Extract() {
+		locus = append(locus, l)
+	}
+	code := truncateToSnippets(src, locus)
+	if len(code) != len(src) {
+		t.Fatalf("len(truncateToSnippets()) = %d, want: %d", len(code), len(src))
+	}
+	for _, l := range locus {
+		span := string(code[l.Span.Start.Offset:l.Span.End.Offset])
+		if !strings.HasPrefix(span, l.Ident) {
+			t.Errorf("%s: span of truncated code = %q", l.Ident, span)
+		}
+		if snippet := code[l.Snippet.Start.Offset:l.Snippet.End.Offset]; string(snippet) != string(src[l.Snippet.Start.Offset:l.Snippet.End.Offset]) {
+			t.Errorf("%s: snippet of truncated code = %q", l.Ident, snippet)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+
+	"contribs-go/model"
+)
+
+// Weights of quality factors of snippets, summing up to 1
+const (
+	weightLength     = 0.25
+	weightComments   = 0.15
+	weightAPIs       = 0.15
+	weightNesting    = 0.15
+	weightCategory   = 0.15
+	weightPopularity = 0.15
+)
+
+// Penalty of generated files
+const generatedPenalty = 0.2
+
+// Quality factors by category, e. g. examples are as instructive as
+// production code
+var categoryFactors = map[string]float64{
+	model.CategoryProduction: 1,
+	model.CategoryExample:    1,
+	model.CategoryTest:       0.6,
+	model.CategoryBenchmark:  0.5,
+	model.CategoryFuzz:       0.5,
+}
+
+// Features of a snippet relevant to its quality as an example
+type snippetStats struct {
+	lines    int
+	comments int // Comment lines
+	depth    int // Nesting depth of blocks, e. g. 1 for a flat function
+	apis     int // Distinct standard library APIs
+}
+
+// Scores locus between 0 and 1 by the quality of its snippet as an example,
+// e. g. short, commented and flat production code of a popular repository
+// using several standard library APIs scores high
+func scoreLocus(locus []model.Locus, src []byte, category string, generated bool, stars int) {
+	stats := findSnippetStats(locus, src)
+	for i, l := range locus {
+		s := stats[l.Snippet]
+
+		score := weightLength*lengthFactor(s.lines) +
+			weightComments*min(float64(s.comments)/max(float64(s.lines), 1)/0.2, 1) +
+			weightAPIs*min(float64(s.apis), 4)/4 +
+			weightNesting*nestingFactor(s.depth) +
+			weightCategory*categoryFactors[category] +
+			// 100k stars and more score fully
+			weightPopularity*min(math.Log10(float64(stars)+1)/5, 1)
+		if generated {
+			score *= generatedPenalty
+		}
+		locus[i].Score = math.Round(score*1000) / 1000
+	}
+}
+
+// Snippets of 5 to 40 lines score fully
+func lengthFactor(lines int) float64 {
+	switch {
+	case lines == 0:
+		return 0
+
+	case lines < 5:
+		return float64(lines) / 5
+
+	case lines > 40:
+		return 40 / float64(lines)
+	}
+	return 1
+}
+
+// Nesting up to 3 blocks, including the function body, scores fully
+func nestingFactor(depth int) float64 {
+	if depth <= 3 {
+		return 1
+	}
+	return 3 / float64(depth)
+}
+
+// Finds features of snippets of locus. Snippets of locus without a top-level
+// declaration are zero.
+func findSnippetStats(locus []model.Locus, src []byte) map[model.Span]snippetStats {
+	stats := make(map[model.Span]snippetStats)
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return stats
+	}
+
+	// Top-level declarations by offset of snippet start, including doc
+	// comments
+	decls := make(map[int]ast.Decl)
+	for _, decl := range file.Decls {
+		start := decl.Pos()
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		}
+		decls[fset.Position(start).Offset] = decl
+	}
+
+	apis := make(map[model.Span]map[string]struct{})
+	for _, l := range locus {
+		if _, ok := apis[l.Snippet]; !ok {
+			apis[l.Snippet] = make(map[string]struct{})
+		}
+		apis[l.Snippet][l.Ident] = struct{}{}
+	}
+	for snippet, idents := range apis {
+		decl, ok := decls[snippet.Start.Offset]
+		if !ok {
+			continue
+		}
+
+		s := snippetStats{
+			lines: snippet.End.Line - snippet.Start.Line + 1,
+			depth: nestingDepth(decl),
+			apis:  len(idents),
+		}
+		for _, group := range file.Comments {
+			start, end := fset.Position(group.Pos()), fset.Position(group.End())
+			if start.Offset < snippet.Start.Offset || end.Offset > snippet.End.Offset {
+				continue
+			}
+			s.comments += end.Line - start.Line + 1
+		}
+		stats[snippet] = s
+	}
+	return stats
+}
+
+// Maximum depth of nested blocks, e. g. 2 for an "if" statement in a function
+// body
+func nestingDepth(node ast.Node) int {
+	var (
+		maxdepth int
+		inspect  func(node ast.Node, depth int)
+	)
+	inspect = func(node ast.Node, depth int) {
+		ast.Inspect(node, func(child ast.Node) bool {
+			if child == node {
+				return true
+			}
+			if block, ok := child.(*ast.BlockStmt); ok {
+				maxdepth = max(maxdepth, depth+1)
+				inspect(block, depth+1)
+				return false
+			}
+			return true
+		})
+	}
+	inspect(node, 0)
+	return maxdepth
+}
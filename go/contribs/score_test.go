@@ -0,0 +1,74 @@
+package main
+
+import (
+	"go/ast"
+	"testing"
+
+	"contribs-go/model"
+)
+
+func TestScoreLocus(t *testing.T) {
+	src := []byte(openTest(t, "score"))
+	extract := func() []model.Locus {
+		ex := newExtractor(src)
+		if ex.Error != nil {
+			t.Fatal(ex.Error)
+		}
+		locus := make([]model.Locus, 0)
+		for l := range ex.Extract() {
+			locus = append(locus, l)
+		}
+		return locus
+	}
+	// Scores by identifier
+	score := func(category string, generated bool, stars int) map[string]float64 {
+		locus := extract()
+		scoreLocus(locus, src, category, generated, stars)
+		scores := make(map[string]float64)
+		for _, l := range locus {
+			if l.Score <= 0 || l.Score > 1 {
+				t.Errorf("%s: score %v out of range", l.Ident, l.Score)
+			}
+			scores[l.Ident] = l.Score
+		}
+		return scores
+	}
+
+	production := score(model.CategoryProduction, false, 1000)
+	// Short, commented and flat
+	if production["os.ReadFile"] <= production["os.Stat"] {
+		t.Errorf("os.ReadFile scores %v, os.Stat scores %v", production["os.ReadFile"], production["os.Stat"])
+	}
+	// Same snippet
+	if production["os.ReadFile"] != production["strings.TrimSpace"] {
+		t.Errorf("os.ReadFile scores %v, strings.TrimSpace scores %v", production["os.ReadFile"], production["strings.TrimSpace"])
+	}
+
+	for name, scores := range map[string]map[string]float64{
+		"test":      score(model.CategoryTest, false, 1000),
+		"generated": score(model.CategoryProduction, true, 1000),
+		"unpopular": score(model.CategoryProduction, false, 0),
+	} {
+		if scores["os.ReadFile"] >= production["os.ReadFile"] {
+			t.Errorf("%s: os.ReadFile scores %v, want less than %v", name, scores["os.ReadFile"], production["os.ReadFile"])
+		}
+	}
+}
+
+func TestNestingDepth(t *testing.T) {
+	ex := newExtractor([]byte(openTest(t, "score")))
+	if ex.Error != nil {
+		t.Fatal(ex.Error)
+	}
+
+	want := map[string]int{"read": 2, "wait": 5}
+	for _, decl := range ex.files[0].Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		if got := nestingDepth(funcDecl); got != want[funcDecl.Name.Name] {
+			t.Errorf("nestingDepth(%s) = %v, want %v", funcDecl.Name.Name, got, want[funcDecl.Name.Name])
+		}
+	}
+}
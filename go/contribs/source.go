@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	filepat "path/filepath"
+	"strings"
+)
+
+// Source of repositories selecting GitHub repositories of the manifest
+const sourceGitHub = "github"
+
+// Repository to extract contributions from, e. g. a GitHub repository, a
+// bare git repository or a local directory
+type repository struct {
+	owner, name string
+	// Cloned for extraction, e. g. "https://github.com/cli/cli.git" or
+	// "file:///srv/git/cli.git". Empty for directories extracted in place.
+	cloneURL string
+	// Directory extracted in place, e. g. a checkout of a private monorepo
+	dir string
+	// Commit of a directory extracted in place. Empty if unknown, e. g.
+	// because of uncommitted changes.
+	commit string
+	// Web page, e. g. "https://github.com/cli/cli". Empty if unknown.
+	url   string
+	stars int
+
+	// Branch or tag to clone, e. g. "v2.40.0". Defaults to HEAD.
+	ref string
+	// Globs of paths to include and exclude, see manifestRepo
+	include, exclude []string
+	limits           limits
+	// License of the manifest, e. g. "MIT license", used if none is detected
+	license string
+	// Policy of the manifest overriding policies by license
+	policy   string
+	policies licensePolicy
+	// Hash of the manifest entry, see manifestRepo.hash. Empty for local
+	// repositories.
+	config string
+}
+
+// Finds repositories of the source, e. g. "github", "/src/monorepo",
+// "/srv/git/cli.git" or "file:///srv/git/cli.git". GitHub repositories are
+// those of the manifest.
+func findSourceRepos(ctx context.Context, source string, man manifest) ([]repository, error) {
+	if source == sourceGitHub {
+		return findRepos(ctx, newGHClient(), man)
+	}
+	repo, err := findLocalRepo(source)
+	if err != nil {
+		return nil, err
+	}
+	repo.policies = man.Policy
+	return []repository{repo}, nil
+}
+
+// Reports whether a file, e. g. "/tmp/cli/cmd/gh/main.go", is extracted by
+// globs and limits
+func (repo repository) includeFile(repoDir, file string, size int64) bool {
+	if repo.limits.MaxFileSize > 0 && size > repo.limits.MaxFileSize {
+		return false
+	}
+	rel, err := filepat.Rel(repoDir, file)
+	if err != nil {
+		return false
+	}
+	return includePath(filepat.ToSlash(rel), repo.include, repo.exclude)
+}
+
+// Decides the policy by the detected SPDX expression, the license of the
+// manifest otherwise. Returns the policy and the SPDX expression, empty if
+// unknown.
+func (repo repository) decidePolicy(spdx string) (string, string) {
+	if spdx == "" {
+		spdx, _ = findSPDX(repo.license)
+	}
+	return repo.policies.decide(repo.policy, spdx), spdx
+}
+
+// Finds a local repository. Bare repositories and "file://" URLs, e. g.
+// mirrors, are cloned. Other directories are extracted in place. Owner, name
+// and web page are taken from the "origin" remote if any, e. g.
+// "git@github.com:cli/cli.git". Otherwise, the owner is "local" and the name
+// is the directory's name.
+func findLocalRepo(source string) (repository, error) {
+	var (
+		repo repository
+		dir  string
+	)
+	if strings.HasPrefix(source, "file://") {
+		repo.cloneURL = source
+		dir = strings.TrimPrefix(source, "file://")
+	} else {
+		abs, err := filepat.Abs(source)
+		if err != nil {
+			return repo, err
+		}
+		dir = abs
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return repo, err
+	}
+	if !info.IsDir() {
+		return repo, fmt.Errorf("%s isn't a directory", source)
+	}
+
+	if repo.cloneURL == "" {
+		if bare, _ := git(dir, "rev-parse", "--is-bare-repository"); bare == "true" {
+			repo.cloneURL = "file://" + dir
+		} else {
+			repo.dir = dir
+		}
+	}
+
+	// Git metadata of directories within other repositories isn't theirs
+	isRepo := repo.cloneURL != ""
+	if !isRepo {
+		top, err := git(dir, "rev-parse", "--show-toplevel")
+		isRepo = err == nil && sameDir(top, dir)
+	}
+	if isRepo {
+		if origin, err := git(dir, "config", "--get", "remote.origin.url"); err == nil {
+			repo.owner, repo.name, repo.url = parseRemoteURL(origin)
+		}
+		if repo.dir != "" {
+			// Commits don't reflect uncommitted changes
+			if status, err := git(dir, "status", "--porcelain"); err == nil && status == "" {
+				repo.commit, _ = headCommit(dir)
+			}
+		}
+	}
+	if repo.owner == "" || repo.name == "" {
+		repo.owner = "local"
+		repo.name = strings.TrimSuffix(filepat.Base(dir), ".git")
+	}
+	return repo, nil
+}
+
+// Parses owner, name and web page of a remote URL, e. g.
+// "https://github.com/cli/cli.git" or "git@github.com:cli/cli.git". The web
+// page is empty for local remotes, e. g. "/srv/git/cli.git".
+func parseRemoteURL(remote string) (owner, name, webURL string) {
+	var host, repoPath string
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return "", "", ""
+		}
+		host, repoPath = u.Hostname(), u.Path
+	} else if userHost, p, ok := strings.Cut(remote, ":"); ok && !strings.Contains(userHost, "/") {
+		// SCP-like syntax, e. g. "git@github.com:cli/cli.git"
+		_, host, _ = strings.Cut(userHost, "@")
+		if host == "" {
+			host = userHost
+		}
+		repoPath = p
+	} else {
+		repoPath = remote
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	dir, name := path.Split(repoPath)
+	owner = path.Base(strings.TrimSuffix(dir, "/"))
+	if dir == "" || name == "" {
+		return "", "", ""
+	}
+	if host != "" {
+		webURL = "https://" + host + "/" + path.Join(dir, name)
+	}
+	return owner, name, webURL
+}
+
+func sameDir(a, b string) bool {
+	a, errA := filepat.EvalSymlinks(a)
+	b, errB := filepat.EvalSymlinks(b)
+	return errA == nil && errB == nil && a == b
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	filepat "path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseRemoteURL(t *testing.T) {
+	tests := []struct {
+		remote string
+
+		owner, name, webURL string
+	}{
+		{"https://github.com/cli/cli.git", "cli", "cli", "https://github.com/cli/cli"},
+		{"https://github.com/cli/cli", "cli", "cli", "https://github.com/cli/cli"},
+		{"ssh://git@github.com/cli/cli.git", "cli", "cli", "https://github.com/cli/cli"},
+		{"git@github.com:cli/cli.git", "cli", "cli", "https://github.com/cli/cli"},
+		{"https://gitlab.example.com/group/sub/app.git", "sub", "app", "https://gitlab.example.com/group/sub/app"},
+		{"file:///srv/git/cli/cli.git", "cli", "cli", ""},
+		{"/srv/git/cli/cli.git", "cli", "cli", ""},
+		{"cli.git", "", "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.remote, func(t *testing.T) {
+			owner, name, webURL := parseRemoteURL(test.remote)
+			if owner != test.owner || name != test.name || webURL != test.webURL {
+				t.Fatalf("parseRemoteURL() = %s, %s, %s, want: %s, %s, %s", owner, name, webURL, test.owner, test.name, test.webURL)
+			}
+		})
+	}
+}
+
+func TestFindLocalRepo(t *testing.T) {
+	mustGit := func(dir string, args ...string) {
+		t.Helper()
+		if _, err := git(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Plain directory
+	plain := filepat.Join(t.TempDir(), "monorepo")
+	if err := os.Mkdir(plain, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepat.Join(plain, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := findLocalRepo(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (repository{owner: "local", name: "monorepo", dir: plain}); !reflect.DeepEqual(repo, want) {
+		t.Fatalf("findLocalRepo() = %+v, want: %+v", repo, want)
+	}
+
+	// Work tree with origin
+	mustGit(plain, "init", "-q")
+	mustGit(plain, "remote", "add", "origin", "git@github.com:cli/cli.git")
+	mustGit(plain, "add", "-A")
+	mustGit(plain, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "test")
+	commit, err := headCommit(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err = findLocalRepo(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (repository{owner: "cli", name: "cli", dir: plain, commit: commit, url: "https://github.com/cli/cli"}); !reflect.DeepEqual(repo, want) {
+		t.Fatalf("findLocalRepo() = %+v, want: %+v", repo, want)
+	}
+
+	// Uncommitted changes
+	if err := os.WriteFile(filepat.Join(plain, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo, err = findLocalRepo(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.commit != "" {
+		t.Fatalf("findLocalRepo() commit = %s, want none", repo.commit)
+	}
+
+	// Subdirectory of a work tree
+	sub := filepat.Join(plain, "cmd")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	repo, err = findLocalRepo(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (repository{owner: "local", name: "cmd", dir: sub}); !reflect.DeepEqual(repo, want) {
+		t.Fatalf("findLocalRepo() = %+v, want: %+v", repo, want)
+	}
+
+	// Bare repository and mirror
+	bare := filepat.Join(t.TempDir(), "cli.git")
+	mustGit("", "clone", "-q", "--bare", plain, bare)
+	for _, source := range []string{bare, "file://" + bare} {
+		repo, err = findLocalRepo(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Bare clones of local repositories keep their path as origin
+		owner, name, _ := parseRemoteURL(plain)
+		if want := (repository{owner: owner, name: name, cloneURL: "file://" + bare}); !reflect.DeepEqual(repo, want) {
+			t.Fatalf("findLocalRepo(%s) = %+v, want: %+v", source, repo, want)
+		}
+	}
+
+	if _, err := findLocalRepo(filepat.Join(plain, "main.go")); err == nil {
+		t.Fatal("want error for file")
+	}
+}